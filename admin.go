@@ -0,0 +1,357 @@
+package cronroutine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdminAction is what a request to the AdminHandler wants to do.
+type AdminAction string
+
+const (
+	// AdminRead lists jobs or reads a job, its history or its next runs.
+	AdminRead    AdminAction = "Read"
+	AdminTrigger AdminAction = "Trigger"
+	AdminPause   AdminAction = "Pause"
+	AdminResume  AdminAction = "Resume"
+	AdminRemove  AdminAction = "Remove"
+)
+
+// AdminHandlerConfig configures an AdminHandler.
+type AdminHandlerConfig struct {
+	// Authorize is called before every request is handled. jobID is empty
+	// when listing jobs. If it returns an error, the request is rejected with
+	// 403 Forbidden and the error's message. If it is nil, every request is
+	// allowed.
+	Authorize func(r *http.Request, action AdminAction, jobID string) error
+}
+
+// AdminHandler is an http.Handler for inspecting and controlling the jobs of a
+// Scheduler. It serves JSON on these paths, relative to where it is mounted:
+//
+//	GET    /jobs                 list jobs, filtered by the group and tag
+//	                             query parameters; tag may be repeated
+//	GET    /jobs/{id}            get a job
+//	DELETE /jobs/{id}            remove a job
+//	GET    /jobs/{id}/history    runs of a job, filtered by the since, until
+//	                             (RFC 3339) and limit query parameters
+//	GET    /jobs/{id}/next       next run times, as many as the count query
+//	                             parameter (default 5, at most 100)
+//	POST   /jobs/{id}/trigger    start a run without waiting for it
+//	POST   /jobs/{id}/pause      pause a job
+//	POST   /jobs/{id}/resume     resume a job
+//
+// POST requests must have a Content-Type of application/json, although they
+// need no body. Browsers only send that cross-origin after a CORS preflight
+// the handler does not answer, so other web pages can not make them.
+//
+// Use http.StripPrefix to mount it under a path other than the root.
+type AdminHandler struct {
+	scheduler *Scheduler
+	authorize func(r *http.Request, action AdminAction, jobID string) error
+}
+
+// NewAdminHandler returns an AdminHandler for s. cfg may be nil.
+func NewAdminHandler(s *Scheduler, cfg *AdminHandlerConfig) *AdminHandler {
+	h := &AdminHandler{scheduler: s}
+	if cfg != nil {
+		h.authorize = cfg.Authorize
+	}
+
+	return h
+}
+
+const (
+	defaultAdminNextCount = 5
+	maxAdminNextCount     = 100
+)
+
+type adminJob struct {
+	ID                string            `json:"id"`
+	Schedule          string            `json:"schedule"`
+	Description       string            `json:"description"`
+	Timeout           string            `json:"timeout,omitempty"`
+	StartingDeadline  string            `json:"starting_deadline,omitempty"`
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy"`
+	MisfirePolicy     MisfirePolicy     `json:"misfire_policy,omitempty"`
+	Group             string            `json:"group,omitempty"`
+	Tags              []string          `json:"tags,omitempty"`
+	Priority          int               `json:"priority,omitempty"`
+	NextRun           time.Time         `json:"next_run"`
+	State             JobState          `json:"state"`
+}
+
+func newAdminJob(j *Job) *adminJob {
+	a := &adminJob{
+		ID:                j.ID(),
+		Schedule:          j.Schedule(),
+		Description:       j.Description(),
+		ConcurrencyPolicy: j.ConcurrencyPolicy(),
+		MisfirePolicy:     j.MisfirePolicy(),
+		Group:             j.Group(),
+		Tags:              j.Tags(),
+		Priority:          j.Priority(),
+		NextRun:           j.NextRun(),
+		State:             j.State(),
+	}
+	if j.Timeout() > 0 {
+		a.Timeout = j.Timeout().String()
+	}
+	if j.StartingDeadline() > 0 {
+		a.StartingDeadline = j.StartingDeadline().String()
+	}
+
+	return a
+}
+
+type adminError struct {
+	Error string `json:"error"`
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments, err := pathSegments(r.URL)
+	if err != nil || len(segments) < 1 || len(segments) > 3 || segments[0] != "jobs" {
+		writeAdminError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if len(segments) == 1 {
+		h.handle(w, r, AdminRead, "", http.MethodGet, h.listJobs)
+		return
+	}
+
+	jobID := segments[1]
+	if len(segments) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			h.handle(w, r, AdminRead, jobID, http.MethodGet, h.getJob)
+		case http.MethodDelete:
+			h.handle(w, r, AdminRemove, jobID, http.MethodDelete, h.removeJob)
+		default:
+			writeMethodNotAllowed(w, r, http.MethodGet, http.MethodDelete)
+		}
+		return
+	}
+
+	switch segments[2] {
+	case "history":
+		h.handle(w, r, AdminRead, jobID, http.MethodGet, h.history)
+	case "next":
+		h.handle(w, r, AdminRead, jobID, http.MethodGet, h.nextRuns)
+	case "trigger":
+		h.handle(w, r, AdminTrigger, jobID, http.MethodPost, h.trigger)
+	case "pause":
+		h.handle(w, r, AdminPause, jobID, http.MethodPost, h.pause)
+	case "resume":
+		h.handle(w, r, AdminResume, jobID, http.MethodPost, h.resume)
+	default:
+		writeAdminError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// handle checks the request's method and authorization, then calls fn and
+// writes its result as JSON.
+func (h *AdminHandler) handle(w http.ResponseWriter, r *http.Request, action AdminAction, jobID string, method string,
+	fn func(r *http.Request, jobID string) (int, any, error),
+) {
+	if r.Method != method {
+		writeMethodNotAllowed(w, r, method)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			writeAdminError(w, http.StatusUnsupportedMediaType, errors.New("content type must be application/json"))
+			return
+		}
+	}
+
+	if h.authorize != nil {
+		if err := h.authorize(r, action, jobID); err != nil {
+			writeAdminError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	status, body, err := fn(r, jobID)
+	if err != nil {
+		writeAdminError(w, status, err)
+		return
+	}
+
+	writeAdminJSON(w, status, body)
+}
+
+func (h *AdminHandler) listJobs(r *http.Request, _ string) (int, any, error) {
+	var filters []JobFilter
+	if group := r.URL.Query().Get("group"); group != "" {
+		filters = append(filters, InGroup(group))
+	}
+	for _, tag := range r.URL.Query()["tag"] {
+		filters = append(filters, HasTag(tag))
+	}
+
+	jobs := h.scheduler.ListJobs(filters...)
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID() < jobs[j].ID() })
+
+	ret := make([]*adminJob, 0, len(jobs))
+	for _, j := range jobs {
+		ret = append(ret, newAdminJob(j))
+	}
+
+	return http.StatusOK, ret, nil
+}
+
+func (h *AdminHandler) getJob(r *http.Request, jobID string) (int, any, error) {
+	job, err := h.scheduler.GetJob(jobID)
+	if err != nil {
+		return adminErrorStatus(err), nil, err
+	}
+
+	return http.StatusOK, newAdminJob(job), nil
+}
+
+func (h *AdminHandler) removeJob(r *http.Request, jobID string) (int, any, error) {
+	if err := h.scheduler.RemoveJob(jobID); err != nil {
+		return adminErrorStatus(err), nil, err
+	}
+
+	return http.StatusNoContent, nil, nil
+}
+
+func (h *AdminHandler) history(r *http.Request, jobID string) (int, any, error) {
+	q := HistoryQuery{JobID: jobID}
+	params := r.URL.Query()
+
+	var err error
+	if v := params.Get("since"); v != "" {
+		if q.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return http.StatusBadRequest, nil, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if v := params.Get("until"); v != "" {
+		if q.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return http.StatusBadRequest, nil, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			return http.StatusBadRequest, nil, fmt.Errorf("invalid limit: %s", v)
+		}
+	}
+
+	// The history of removed jobs is still served, so the job does not have
+	// to exist.
+	runs, err := h.scheduler.History(q)
+	if err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+
+	ret := make([]*historyRecord, 0, len(runs))
+	for _, run := range runs {
+		ret = append(ret, newHistoryRecord(run))
+	}
+
+	return http.StatusOK, ret, nil
+}
+
+func (h *AdminHandler) nextRuns(r *http.Request, jobID string) (int, any, error) {
+	count := defaultAdminNextCount
+	if v := r.URL.Query().Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxAdminNextCount {
+			return http.StatusBadRequest, nil, fmt.Errorf("invalid count: %s", v)
+		}
+		count = n
+	}
+
+	job, err := h.scheduler.GetJob(jobID)
+	if err != nil {
+		return adminErrorStatus(err), nil, err
+	}
+
+	return http.StatusOK, job.NextN(count), nil
+}
+
+func (h *AdminHandler) trigger(r *http.Request, jobID string) (int, any, error) {
+	// The run must outlive the request, so it does not use its context.
+	if _, err := h.scheduler.TriggerJobAsync(context.Background(), jobID); err != nil {
+		return adminErrorStatus(err), nil, err
+	}
+
+	return http.StatusAccepted, nil, nil
+}
+
+func (h *AdminHandler) pause(r *http.Request, jobID string) (int, any, error) {
+	if err := h.scheduler.PauseJob(jobID); err != nil {
+		return adminErrorStatus(err), nil, err
+	}
+
+	return h.getJob(r, jobID)
+}
+
+func (h *AdminHandler) resume(r *http.Request, jobID string) (int, any, error) {
+	if err := h.scheduler.ResumeJob(jobID); err != nil {
+		return adminErrorStatus(err), nil, err
+	}
+
+	return h.getJob(r, jobID)
+}
+
+// pathSegments splits the path of u into its unescaped segments, so that job
+// IDs may contain escaped slashes.
+func pathSegments(u *url.URL) ([]string, error) {
+	path := strings.Trim(u.EscapedPath(), "/")
+	if path == "" {
+		return nil, nil
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = unescaped
+	}
+
+	return segments, nil
+}
+
+func adminErrorStatus(err error) int {
+	if errors.As(err, &ErrJobNotFound{}) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+}
+
+func writeAdminError(w http.ResponseWriter, status int, err error) {
+	writeAdminJSON(w, status, &adminError{Error: err.Error()})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, body any) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
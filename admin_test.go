@@ -0,0 +1,172 @@
+package cronroutine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func adminRequest(t *testing.T, server *httptest.Server, method, path string, body any) int {
+	req, err := http.NewRequest(method, server.URL+path, nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-User", "admin")
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	if body != nil {
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(body))
+	}
+
+	return resp.StatusCode
+}
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	ran := make(chan struct{}, 1)
+	for _, id := range []string{"test-1", "test/0"} {
+		err := scheduler.AddJob(JobConfig{
+			ID:                id,
+			Schedule:          "0 0 1 1 *",
+			Timeout:           time.Minute,
+			ConcurrencyPolicy: ConcurrencyForbid,
+			Func: func(ctx context.Context) error {
+				ran <- struct{}{}
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	actions := []AdminAction{}
+	server := httptest.NewServer(http.StripPrefix("/admin", NewAdminHandler(scheduler, &AdminHandlerConfig{
+		Authorize: func(r *http.Request, action AdminAction, jobID string) error {
+			actions = append(actions, action)
+			if r.Header.Get("X-User") != "admin" && action != AdminRead {
+				return errors.New("only admins can change jobs")
+			}
+			return nil
+		},
+	})))
+	defer server.Close()
+
+	jobs := []*adminJob{}
+	assert.Equal(t, http.StatusOK, adminRequest(t, server, http.MethodGet, "/admin/jobs", &jobs))
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, "test-1", jobs[0].ID)
+	assert.Equal(t, "test/0", jobs[1].ID)
+	assert.Equal(t, "0 0 1 1 *", jobs[1].Schedule)
+	assert.Equal(t, "At 00:00, on day 1 of the month, in January", jobs[1].Description)
+	assert.Equal(t, "1m0s", jobs[1].Timeout)
+	assert.Equal(t, ConcurrencyForbid, jobs[1].ConcurrencyPolicy)
+
+	job := &adminJob{}
+	assert.Equal(t, http.StatusOK, adminRequest(t, server, http.MethodGet, "/admin/jobs/test%2F0", job))
+	assert.Equal(t, "test/0", job.ID)
+	assert.Equal(t, time.Month(1), job.NextRun.Month())
+
+	next := []time.Time{}
+	assert.Equal(t, http.StatusOK, adminRequest(t, server, http.MethodGet, "/admin/jobs/test%2F0/next?count=3", &next))
+	assert.Len(t, next, 3)
+	assert.Equal(t, job.NextRun, next[0])
+	assert.Equal(t, next[0].AddDate(1, 0, 0), next[1])
+
+	assert.Equal(t, http.StatusAccepted, adminRequest(t, server, http.MethodPost, "/admin/jobs/test%2F0/trigger", nil))
+	<-ran
+	assert.Eventually(t, func() bool {
+		history := []*historyRecord{}
+		assert.Equal(t, http.StatusOK, adminRequest(t, server, http.MethodGet, "/admin/jobs/test%2F0/history?limit=5", &history))
+		return len(history) == 1 && history[0].Status == RunSucceeded && history[0].Trigger == TriggerManual
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, adminRequest(t, server, http.MethodPost, "/admin/jobs/test%2F0/pause", job))
+	assert.True(t, job.State.Paused)
+	assert.Equal(t, http.StatusOK, adminRequest(t, server, http.MethodPost, "/admin/jobs/test%2F0/resume", job))
+	assert.False(t, job.State.Paused)
+
+	assert.Equal(t, http.StatusNoContent, adminRequest(t, server, http.MethodDelete, "/admin/jobs/test%2F0", nil))
+	apiErr := &adminError{}
+	assert.Equal(t, http.StatusNotFound, adminRequest(t, server, http.MethodGet, "/admin/jobs/test%2F0", apiErr))
+	assert.Equal(t, "job with ID test/0 does not exist", apiErr.Error)
+
+	assert.Equal(t, []AdminAction{
+		AdminRead, AdminRead, AdminRead, AdminTrigger, AdminRead, AdminPause, AdminResume, AdminRemove, AdminRead,
+	}, actions)
+}
+
+func TestAdminHandler_errors(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	err := scheduler.AddJob(JobConfig{
+		ID:       "test-0",
+		Schedule: "0 0 1 1 *",
+		Func:     func(ctx context.Context) error { return nil },
+	})
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(NewAdminHandler(scheduler, &AdminHandlerConfig{
+		Authorize: func(r *http.Request, action AdminAction, jobID string) error {
+			if action == AdminRemove {
+				return errors.New("jobs can not be removed")
+			}
+			return nil
+		},
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		errMsg string
+	}{
+		{http.MethodGet, "/", http.StatusNotFound, "not found"},
+		{http.MethodGet, "/jobs/test-0/unknown", http.StatusNotFound, "not found"},
+		{http.MethodGet, "/jobs/does-not-exist", http.StatusNotFound, "job with ID does-not-exist does not exist"},
+		{http.MethodPost, "/jobs/does-not-exist/trigger", http.StatusNotFound, "job with ID does-not-exist does not exist"},
+		{http.MethodGet, "/jobs/test-0/trigger", http.StatusMethodNotAllowed, "method GET not allowed"},
+		{http.MethodGet, "/jobs/test-0/next?count=1000", http.StatusBadRequest, "invalid count: 1000"},
+		{http.MethodGet, "/jobs/test-0/history?since=yesterday", http.StatusBadRequest, "invalid since"},
+		{http.MethodDelete, "/jobs/test-0", http.StatusForbidden, "jobs can not be removed"},
+	}
+
+	for _, tt := range tests {
+		apiErr := &adminError{}
+		assert.Equal(t, tt.status, adminRequest(t, server, tt.method, tt.path, apiErr), tt.path)
+		assert.True(t, strings.HasPrefix(apiErr.Error, tt.errMsg), apiErr.Error)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/jobs/test-0", nil)
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, "GET, DELETE", resp.Header.Get("Allow"))
+
+	// A form or a fetch without a preflight can not set a JSON content
+	// type, so such requests are rejected.
+	resp, err = http.Post(server.URL+"/jobs/test-0/pause", "text/plain", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	job, err := scheduler.GetJob("test-0")
+	assert.NoError(t, err)
+	assert.False(t, job.Paused())
+
+	_, err = scheduler.GetJob("test-0")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,495 @@
+// Command cronroutine validates and previews cron schedules, and runs the
+// commands of crontab files like a small cron daemon.
+//
+// Usage:
+//
+//	cronroutine validate [-f] ARG...
+//	cronroutine next [-n COUNT] [-tz ZONE] [-from TIME] EXPR
+//	cronroutine describe [-tz ZONE] EXPR
+//	cronroutine diff [-for DURATION] [-tz ZONE] [-from TIME] EXPR EXPR
+//	cronroutine run [-http ADDR] [-control] [-workers N] FILE...
+//
+// EXPR is a cron expression with five fields. Quote it, or pass its fields as
+// separate arguments when it is the only one.
+//
+// run loads the crontab files and runs their commands until it is
+// interrupted. With -http it serves a dashboard of the jobs on ADDR, and their
+// metrics in the Prometheus text format on /metrics. An ADDR that is only a
+// port, such as :8080, listens on localhost; give a host such as 0.0.0.0:8080
+// to listen on other interfaces. The dashboard is read-only unless -control is
+// given, which lets anyone who can reach it trigger, pause, resume and remove
+// jobs.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/drewgonzales360/cronroutine"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+)
+
+const usage = `Usage:
+  cronroutine validate [-f] ARG...
+        check that cron expressions, or with -f crontab files, are valid
+  cronroutine next [-n COUNT] [-tz ZONE] [-from TIME] EXPR
+        print the next times EXPR fires
+  cronroutine describe [-tz ZONE] EXPR
+        describe EXPR in English
+  cronroutine diff [-for DURATION] [-tz ZONE] [-from TIME] EXPR EXPR
+        compare the times two expressions fire
+  cronroutine run [-http ADDR] [-control] [-workers N] FILE...
+        run the commands of crontab files until interrupted
+`
+
+// Exit codes. Like diff, validate and diff exit with 1 when they find a
+// problem or a difference.
+const (
+	exitOK      = 0
+	exitFailed  = 1
+	exitInvalid = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitInvalid
+	}
+
+	var err error
+	code := exitOK
+	switch args[0] {
+	case "validate":
+		code, err = validate(args[1:], stdout, stderr)
+	case "next":
+		err = next(args[1:], stdout, stderr)
+	case "describe":
+		err = describe(args[1:], stdout, stderr)
+	case "diff":
+		code, err = diff(args[1:], stdout, stderr)
+	case "run":
+		err = daemon(args[1:], stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		err = usageError{fmt.Errorf("unknown command %q", args[0])}
+	}
+
+	var usageErr usageError
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "cronroutine: %v\n\n%s", err, usage)
+		return exitInvalid
+	case err != nil:
+		fmt.Fprintf(stderr, "cronroutine: %v\n", err)
+		return exitInvalid
+	}
+
+	return code
+}
+
+// usageError is an error in how the command was called.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+
+// timeFlags are the flags shared by the commands that compute fire times.
+type timeFlags struct {
+	tz   string
+	from string
+}
+
+func (f *timeFlags) register(fs *flag.FlagSet, from bool) {
+	fs.StringVar(&f.tz, "tz", "UTC", "time zone to evaluate the schedule in, such as America/New_York")
+	if from {
+		fs.StringVar(&f.from, "from", "", "RFC 3339 time to start from (default now)")
+	}
+}
+
+func (f *timeFlags) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(f.tz)
+	if err != nil {
+		return nil, usageError{fmt.Errorf("invalid time zone: %w", err)}
+	}
+
+	return loc, nil
+}
+
+func (f *timeFlags) start() (time.Time, error) {
+	if f.from == "" {
+		return time.Now(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, f.from)
+	if err != nil {
+		return time.Time{}, usageError{fmt.Errorf("invalid -from: %w", err)}
+	}
+
+	return t, nil
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parse parses a cron expression given on the command line and evaluates it
+// in loc.
+func parse(expr string, loc *time.Location) (*cronroutine.Cron, error) {
+	cron, err := cronroutine.ParseCron(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	cron.Location = loc
+
+	return cron, nil
+}
+
+func validate(args []string, stdout, stderr io.Writer) (int, error) {
+	fs := newFlagSet("validate", stderr)
+	files := fs.Bool("f", false, "treat the arguments as crontab files; - reads standard input")
+	if err := fs.Parse(args); err != nil {
+		return exitInvalid, err
+	}
+	if fs.NArg() == 0 {
+		return exitInvalid, usageError{errors.New("validate needs at least one argument")}
+	}
+
+	valid := true
+	if !*files {
+		for _, expr := range fs.Args() {
+			if _, err := cronroutine.ParseCron(expr); err != nil {
+				valid = false
+				reportExpression(stdout, expr, err)
+			}
+		}
+	} else {
+		for _, path := range fs.Args() {
+			ok, err := validateFile(path, stdout)
+			if err != nil {
+				return exitInvalid, err
+			}
+			valid = valid && ok
+		}
+	}
+
+	if !valid {
+		return exitFailed, nil
+	}
+
+	return exitOK, nil
+}
+
+// reportExpression prints err with a caret under the field that could not be
+// parsed.
+func reportExpression(w io.Writer, expr string, err error) {
+	fmt.Fprintf(w, "%s\n  %s\n", err, expr)
+
+	var parseErr *cronroutine.ParseError
+	if errors.As(err, &parseErr) {
+		fmt.Fprintf(w, "  %s^\n", strings.Repeat(" ", parseErr.Offset))
+	}
+}
+
+// validateFile parses the crontab at path with cronroutine.ParseCrontab and
+// reports the position of every line that is invalid.
+func validateFile(path string, stdout io.Writer) (bool, error) {
+	r := io.Reader(os.Stdin)
+	if path == "-" {
+		path = "<stdin>"
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	_, err := cronroutine.ParseCrontab(r, path)
+	var crontabErr *cronroutine.CrontabError
+	if !errors.As(err, &crontabErr) {
+		return err == nil, err
+	}
+
+	// ParseCrontab joins the errors of all of the invalid lines.
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		if errors.As(err, &crontabErr) {
+			fmt.Fprintf(stdout, "%s:%d:%d: %v\n", crontabErr.Name, crontabErr.Line, crontabErr.Column, crontabErr.Err)
+		}
+	}
+
+	return false, nil
+}
+
+func next(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("next", stderr)
+	count := fs.Int("n", 10, "number of fire times to print")
+	var tf timeFlags
+	tf.register(fs, true)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError{errors.New("next needs an expression")}
+	}
+
+	loc, err := tf.location()
+	if err != nil {
+		return err
+	}
+	start, err := tf.start()
+	if err != nil {
+		return err
+	}
+	cron, err := parse(strings.Join(fs.Args(), " "), loc)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range fireTimes(cron, start, *count, 0) {
+		fmt.Fprintln(stdout, t.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func describe(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("describe", stderr)
+	var tf timeFlags
+	tf.register(fs, false)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError{errors.New("describe needs an expression")}
+	}
+
+	loc, err := tf.location()
+	if err != nil {
+		return err
+	}
+	cron, err := parse(strings.Join(fs.Args(), " "), loc)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, cron.Describe())
+	return nil
+}
+
+// maxDiffTimes is the most fire times of each expression that diff compares,
+// so that a long window on a frequent schedule can not run away.
+const maxDiffTimes = 100000
+
+func diff(args []string, stdout, stderr io.Writer) (int, error) {
+	fs := newFlagSet("diff", stderr)
+	window := fs.Duration("for", 7*24*time.Hour, "how far ahead to compare")
+	var tf timeFlags
+	tf.register(fs, true)
+	if err := fs.Parse(args); err != nil {
+		return exitInvalid, err
+	}
+	if fs.NArg() != 2 {
+		return exitInvalid, usageError{errors.New("diff needs two quoted expressions")}
+	}
+
+	loc, err := tf.location()
+	if err != nil {
+		return exitInvalid, err
+	}
+	start, err := tf.start()
+	if err != nil {
+		return exitInvalid, err
+	}
+	a, err := parse(fs.Arg(0), loc)
+	if err != nil {
+		return exitInvalid, err
+	}
+	b, err := parse(fs.Arg(1), loc)
+	if err != nil {
+		return exitInvalid, err
+	}
+
+	timesA := fireTimes(a, start, maxDiffTimes, *window)
+	timesB := fireTimes(b, start, maxDiffTimes, *window)
+
+	// Both lists are sorted, so walk them together like a merge.
+	onlyA, onlyB := 0, 0
+	for len(timesA) > 0 || len(timesB) > 0 {
+		switch {
+		case len(timesB) == 0 || (len(timesA) > 0 && timesA[0].Before(timesB[0])):
+			fmt.Fprintf(stdout, "- %s\n", timesA[0].Format(time.RFC3339))
+			timesA = timesA[1:]
+			onlyA++
+		case len(timesA) == 0 || timesB[0].Before(timesA[0]):
+			fmt.Fprintf(stdout, "+ %s\n", timesB[0].Format(time.RFC3339))
+			timesB = timesB[1:]
+			onlyB++
+		default:
+			timesA, timesB = timesA[1:], timesB[1:]
+		}
+	}
+
+	if onlyA == 0 && onlyB == 0 {
+		fmt.Fprintf(stdout, "no differences in the next %s\n", *window)
+		return exitOK, nil
+	}
+
+	fmt.Fprintf(stdout, "%d only in %q, %d only in %q\n", onlyA, fs.Arg(0), onlyB, fs.Arg(1))
+	return exitFailed, nil
+}
+
+// fireTimes returns up to count fire times of cron after start, and within
+// window of it if window is not zero.
+func fireTimes(cron *cronroutine.Cron, start time.Time, count int, window time.Duration) []time.Time {
+	times := []time.Time{}
+	for t := start; len(times) < count; {
+		t = cron.NextAfter(t)
+		if t.IsZero() || (window > 0 && !t.Before(start.Add(window))) {
+			break
+		}
+		times = append(times, t)
+	}
+
+	return times
+}
+
+func daemon(args []string, stderr io.Writer) error {
+	fs := newFlagSet("run", stderr)
+	addr := fs.String("http", "", "address to serve the dashboard and /metrics on; a port alone, such as :8080, listens on localhost")
+	control := fs.Bool("control", false, "let the dashboard trigger, pause, resume and remove jobs")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of commands that can run at once")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError{errors.New("run needs at least one crontab file")}
+	}
+
+	jobs, err := loadCrontabs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	zapLogger, err := zap.NewProduction()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = zapLogger.Sync() }()
+	logger := zapr.NewLogger(zapLogger)
+
+	cfg := cronroutine.DefaultSchedulerConfig()
+	cfg.Logger = logger
+	cfg.WorkerCount = *workers
+	metrics := cronroutine.NewPrometheusMetrics()
+	cfg.Metrics = metrics
+	scheduler := cronroutine.StartNewScheduler(cfg)
+	defer func() { _ = cronroutine.StopScheduler(scheduler) }()
+
+	for _, job := range jobs {
+		if err := scheduler.AddJob(job); err != nil {
+			return err
+		}
+		logger.Info("added job", "job_id", job.ID, "schedule", job.Schedule)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/", cronroutine.NewDashboardHandler(scheduler, dashboardConfig(*control)))
+		mux.Handle("/metrics", metrics)
+		server := &http.Server{Addr: listenAddr(*addr), Handler: mux}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error(err, "failed to serve", "addr", server.Addr)
+				stop()
+			}
+		}()
+		defer func() { _ = server.Close() }()
+		logger.Info("serving dashboard", "addr", server.Addr, "control", *control)
+	}
+
+	<-ctx.Done()
+	logger.Info("stopping")
+	return nil
+}
+
+// listenAddr returns the address to serve the dashboard on for the -http flag.
+// An address without a host listens on localhost rather than on every
+// interface.
+func listenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+
+	return net.JoinHostPort("localhost", port)
+}
+
+// dashboardConfig returns the configuration of the dashboard. Unless control
+// is true, it only allows requests that read the jobs.
+func dashboardConfig(control bool) *cronroutine.AdminHandlerConfig {
+	if control {
+		return nil
+	}
+
+	return &cronroutine.AdminHandlerConfig{
+		Authorize: func(r *http.Request, action cronroutine.AdminAction, jobID string) error {
+			if action != cronroutine.AdminRead {
+				return errors.New("the dashboard is read-only; run with -control to change jobs")
+			}
+			return nil
+		},
+	}
+}
+
+// loadCrontabs parses the crontab files at paths and returns all of their
+// jobs.
+func loadCrontabs(paths []string) ([]cronroutine.JobConfig, error) {
+	var jobs []cronroutine.JobConfig
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fileJobs, err := cronroutine.ParseCrontab(f, path)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, fileJobs...)
+	}
+
+	return jobs, nil
+}
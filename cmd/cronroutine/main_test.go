@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drewgonzales360/cronroutine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	crontab := filepath.Join(t.TempDir(), "crontab")
+	err := os.WriteFile(crontab, []byte(
+		"SHELL=/bin/sh\n"+
+			"# nightly\n"+
+			"0 0 * * *   /usr/bin/backup --all\n"+
+			"\n"+
+			"  0 24 * * * /bin/true\n"+
+			"1 2 3\n"+
+			"@daily /bin/true\n"+
+			"@reboot /bin/true\n"), 0o644)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected string
+	}{
+		{
+			name:     "validate",
+			args:     []string{"validate", "0 9 * * 1-5", "30 9 * * *"},
+			code:     exitOK,
+			expected: "",
+		},
+		{
+			name: "validate reports the field",
+			args: []string{"validate", "0  25 * * *"},
+			code: exitFailed,
+			expected: "failed to parse hour: failed to parse number: value 25 is not in range 0-23\n" +
+				"  0  25 * * *\n" +
+				"     ^\n",
+		},
+		{
+			name: "validate files",
+			args: []string{"validate", "-f", crontab},
+			code: exitFailed,
+			expected: crontab + ":5:5: failed to parse hour: failed to parse number: value 24 is not in range 0-23\n" +
+				crontab + ":6:6: given 3 but need 5 fields for valid cron config: 1 2 3\n" +
+				crontab + ":8:1: unknown macro @reboot\n",
+		},
+		{
+			name: "next in a time zone",
+			args: []string{"next", "-n", "3", "-tz", "America/New_York", "-from", "2024-03-09T12:00:00Z", "30", "9", "*", "*", "*"},
+			code: exitOK,
+			expected: "2024-03-09T09:30:00-05:00\n" +
+				"2024-03-10T09:30:00-04:00\n" +
+				"2024-03-11T09:30:00-04:00\n",
+		},
+		{
+			name:     "describe",
+			args:     []string{"describe", "30 9 * * 1-5"},
+			code:     exitOK,
+			expected: "At 09:30, on Monday through Friday\n",
+		},
+		{
+			name: "diff",
+			args: []string{"diff", "-for", "24h", "-from", "2024-03-29T00:00:00Z", "0 9-17/2 * * *", "0 9,13,17,20 * * *"},
+			code: exitFailed,
+			expected: "- 2024-03-29T11:00:00Z\n" +
+				"- 2024-03-29T15:00:00Z\n" +
+				"+ 2024-03-29T20:00:00Z\n" +
+				"2 only in \"0 9-17/2 * * *\", 1 only in \"0 9,13,17,20 * * *\"\n",
+		},
+		{
+			name:     "no diff",
+			args:     []string{"diff", "-for", "24h", "0 0 * * *", "0 0 * * 0-6"},
+			code:     exitOK,
+			expected: "no differences in the next 24h0m0s\n",
+		},
+		{
+			name: "invalid time zone",
+			args: []string{"next", "-tz", "Nowhere/Special", "* * * * *"},
+			code: exitInvalid,
+		},
+		{
+			name:     "run an invalid crontab",
+			args:     []string{"run", crontab},
+			code:     exitInvalid,
+			expected: "",
+		},
+		{
+			name: "unknown command",
+			args: []string{"bogus"},
+			code: exitInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.code, run(tt.args, &stdout, &stderr), stderr.String())
+			assert.Equal(t, tt.expected, stdout.String())
+		})
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	assert.Equal(t, "localhost:8080", listenAddr(":8080"))
+	assert.Equal(t, "0.0.0.0:8080", listenAddr("0.0.0.0:8080"))
+	assert.Equal(t, "[::1]:8080", listenAddr("[::1]:8080"))
+}
+
+func TestDashboardConfig(t *testing.T) {
+	assert.Nil(t, dashboardConfig(true))
+
+	authorize := dashboardConfig(false).Authorize
+	assert.NoError(t, authorize(nil, cronroutine.AdminRead, "job"))
+	for _, action := range []cronroutine.AdminAction{cronroutine.AdminTrigger, cronroutine.AdminPause, cronroutine.AdminResume, cronroutine.AdminRemove} {
+		assert.Error(t, authorize(nil, action, "job"))
+	}
+}
@@ -0,0 +1,107 @@
+package cronroutine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandWaitDelay is how long a CommandJob waits for the output of its
+// process to close after the process was killed or exited, in case something
+// outside its process group still holds it open.
+const commandWaitDelay = 5 * time.Second
+
+// CommandConfig describes the process a CommandJob runs.
+type CommandConfig struct {
+	// Args are the program and its arguments. The program is looked up in
+	// PATH if it does not contain a path separator.
+	Args []string
+
+	// Env is added to the environment of the current process, with later
+	// values of a variable replacing earlier ones.
+	Env []string
+
+	// Dir is the working directory of the process. If it is empty, the
+	// process runs in the current directory.
+	Dir string
+
+	// Stdin is written to the standard input of the process.
+	Stdin string
+}
+
+// CommandJob returns a JobFunc that runs the process cfg describes. The
+// combined standard output and standard error of the process is recorded as
+// the result of the run as it is written, so that a process that is killed
+// still leaves its output in the run's history. Only the first bytes that fit
+// in a result are kept.
+//
+// When the run's context is done, for example because the job's Timeout
+// expired, the process is killed along with every process it started, on
+// systems that have process groups. A process that exits with a status other
+// than zero fails the run with ErrCommandFailed, and one that is killed by a
+// signal fails it with ErrCommandKilled.
+func CommandJob(cfg CommandConfig) JobFunc {
+	return func(ctx context.Context) error {
+		if len(cfg.Args) == 0 {
+			return errors.New("command has no arguments")
+		}
+
+		output := &resultWriter{ctx: ctx, limit: maxResultSize}
+		cmd := exec.CommandContext(ctx, cfg.Args[0], cfg.Args[1:]...)
+		cmd.Env = append(os.Environ(), cfg.Env...)
+		cmd.Dir = cfg.Dir
+		cmd.Stdout = output
+		cmd.Stderr = output
+		cmd.WaitDelay = commandWaitDelay
+		if cfg.Stdin != "" {
+			cmd.Stdin = strings.NewReader(cfg.Stdin)
+		}
+		setProcessGroup(cmd)
+
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &exitErr):
+			if signal, ok := exitSignal(exitErr); ok {
+				return ErrCommandKilled{Signal: signal}
+			}
+			return ErrCommandFailed{ExitCode: exitErr.ExitCode()}
+		default:
+			return fmt.Errorf("failed to run command: %w", err)
+		}
+	}
+}
+
+// resultWriter keeps the first limit bytes written to it and discards the
+// rest, so that a command with a lot of output can not use up memory. It sets
+// what it kept as the result of the run ctx belongs to after every write.
+type resultWriter struct {
+	ctx   context.Context
+	lock  sync.Mutex
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *resultWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if n := w.limit - w.buf.Len(); n > 0 {
+		w.buf.Write(p[:min(n, len(p))])
+		SetResult(w.ctx, w.buf.String())
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,15 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package cronroutine
+
+import "os/exec"
+
+// setProcessGroup does nothing where there are no process groups; canceling
+// cmd only kills its process.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// exitSignal reports that no signal killed the process of err, since it can
+// not be told where there are no signals.
+func exitSignal(err *exec.ExitError) (string, bool) {
+	return "", false
+}
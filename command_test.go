@@ -0,0 +1,126 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandJob(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		cfg    CommandConfig
+		result string
+		err    error
+	}{
+		{
+			name: "env, dir and stdin",
+			cfg: CommandConfig{
+				Args:  []string{"sh", "-c", `echo "$GREETING from $(pwd)"; cat; echo oops >&2`},
+				Env:   []string{"GREETING=hello", "GREETING=hi"},
+				Dir:   dir,
+				Stdin: "input\n",
+			},
+			result: "hi from " + dir + "\ninput\noops\n",
+		},
+		{
+			name:   "exit status",
+			cfg:    CommandConfig{Args: []string{"sh", "-c", "echo failing; exit 3"}},
+			result: "failing\n",
+			err:    ErrCommandFailed{ExitCode: 3},
+		},
+		{
+			name: "signal",
+			cfg:  CommandConfig{Args: []string{"sh", "-c", "kill -TERM $$"}},
+			err:  ErrCommandKilled{Signal: "terminated"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			scheduler := newTestScheduler(1)
+			assert.NoError(t, scheduler.AddJob(JobConfig{ID: "cmd", Schedule: "0 0 1 1 *", Func: CommandJob(tt.cfg)}))
+
+			err := scheduler.TriggerJob(context.Background(), "cmd")
+			assert.Equal(t, tt.err, err)
+
+			job, err := scheduler.GetJob("cmd")
+			assert.NoError(t, err)
+			if history := job.History(); assert.Len(t, history, 1) {
+				assert.Equal(t, tt.result, history[0].Result())
+			}
+		})
+	}
+}
+
+func TestCommandJob_notFound(t *testing.T) {
+	t.Parallel()
+	err := CommandJob(CommandConfig{Args: []string{"/does/not/exist"}})(context.Background())
+	assert.ErrorContains(t, err, "failed to run command")
+	assert.False(t, errors.As(err, &ErrCommandFailed{}))
+}
+
+func TestCommandJob_timeoutKillsProcessGroup(t *testing.T) {
+	t.Parallel()
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	scheduler := newTestScheduler(1)
+	err := scheduler.AddJob(JobConfig{
+		ID:       "cmd",
+		Schedule: "0 0 1 1 *",
+		Timeout:  500 * time.Millisecond,
+		Func: CommandJob(CommandConfig{
+			Args: []string{"sh", "-c", `sleep 60 & echo $! > "$PID_FILE"; echo started; wait`},
+			Env:  []string{"PID_FILE=" + pidFile},
+		}),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrJobTimeout{}, scheduler.TriggerJob(context.Background(), "cmd"))
+
+	job, err := scheduler.GetJob("cmd")
+	assert.NoError(t, err)
+	if history := job.History(); assert.Len(t, history, 1) {
+		assert.Equal(t, "started\n", history[0].Result(), "output written before the timeout is kept")
+	}
+
+	data, err := os.ReadFile(pidFile)
+	assert.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	assert.NoError(t, err)
+
+	// The child is gone once it has been killed, or is a zombie if nothing
+	// has reaped it yet.
+	assert.Eventually(t, func() bool {
+		if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+			return true
+		}
+		stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+		return err == nil && strings.Contains(string(stat), ") Z ")
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func Test_resultWriter(t *testing.T) {
+	w := &resultWriter{ctx: context.Background(), limit: 5}
+	n, err := w.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	n, err = w.Write([]byte("defgh"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "abcde", w.buf.String())
+}
@@ -0,0 +1,207 @@
+package cronroutine
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConcurrencyPolicy determines what happens when a job is due to run while a
+// previous run of the same job has not finished yet.
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyAllow lets runs of the same job overlap.
+	ConcurrencyAllow ConcurrencyPolicy = "Allow"
+
+	// ConcurrencyForbid skips the new run if a previous run is still active.
+	ConcurrencyForbid ConcurrencyPolicy = "Forbid"
+
+	// ConcurrencyReplace cancels any active runs and starts the new one.
+	ConcurrencyReplace ConcurrencyPolicy = "Replace"
+
+	// ConcurrencyQueue makes the new run wait for the active run to finish.
+	// At most JobConfig.QueueDepth runs may wait at once.
+	ConcurrencyQueue ConcurrencyPolicy = "Queue"
+)
+
+// validate returns an error if p is not one of the policies above. The empty
+// policy is valid and selects the default.
+func (p ConcurrencyPolicy) validate() error {
+	switch p {
+	case "", ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace, ConcurrencyQueue:
+		return nil
+	default:
+		return fmt.Errorf("unknown concurrency policy %q", p)
+	}
+}
+
+// startRun applies the job's starting deadline and concurrency policy to r. On
+// success it returns the context the run should use and a function that must
+// be called once the run is over.
+func (j *jobMetadata) startRun(ctx context.Context, r *scheduledJob) (context.Context, func(), error) {
+	if j.jobConfig.StartingDeadline > 0 {
+		if time.Now().UTC().After(r.startTime.Add(j.jobConfig.StartingDeadline)) {
+			return nil, nil, ErrPastStartingDeadline{}
+		}
+	}
+
+	j.runLock.Lock()
+	switch j.jobConfig.concurrencyPolicy() {
+	case ConcurrencyForbid:
+		if j.busyLocked() {
+			j.runLock.Unlock()
+			return nil, nil, ErrJobRunning{}
+		}
+	case ConcurrencyReplace:
+		for _, cancel := range j.active {
+			cancel(ErrJobReplaced{})
+		}
+	case ConcurrencyQueue:
+		// awaitTurn reserved the slot before the run took a worker.
+		if r.turn {
+			r.turn = false
+			j.reserved--
+		}
+	}
+
+	id := j.nextRunID
+	j.nextRunID++
+	runCtx, cancel := context.WithCancelCause(ctx)
+	j.active[id] = cancel
+	j.runLock.Unlock()
+
+	release := func() {
+		cancel(nil)
+
+		j.runLock.Lock()
+		defer j.runLock.Unlock()
+		delete(j.active, id)
+		j.handOffLocked()
+	}
+
+	return runCtx, release, nil
+}
+
+// forbidden returns ErrJobRunning if the job does not allow concurrent runs and
+// is currently running.
+func (j *jobMetadata) forbidden() error {
+	if j.jobConfig.concurrencyPolicy() != ConcurrencyForbid {
+		return nil
+	}
+
+	j.runLock.Lock()
+	defer j.runLock.Unlock()
+	if j.busyLocked() {
+		return ErrJobRunning{}
+	}
+
+	return nil
+}
+
+func (j *jobMetadata) busyLocked() bool {
+	return len(j.active) > 0 || j.reserved > 0 || len(j.waiting) > 0
+}
+
+// awaitTurn waits until r may start if the job has the Queue concurrency
+// policy, and reserves the slot for r. It is called before r is given to the
+// dispatcher, so that queued runs do not hold workers that other jobs could
+// use while they wait. For other policies it does nothing.
+func (j *jobMetadata) awaitTurn(ctx context.Context, r *scheduledJob) error {
+	if j.jobConfig.concurrencyPolicy() != ConcurrencyQueue {
+		return nil
+	}
+
+	var deadline time.Time
+	if j.jobConfig.StartingDeadline > 0 {
+		deadline = r.startTime.Add(j.jobConfig.StartingDeadline)
+	}
+
+	j.runLock.Lock()
+	if j.busyLocked() {
+		if err := j.waitForTurn(ctx, deadline); err != nil {
+			return err
+		}
+		// waitForTurn returns with runLock held and the slot reserved
+		// for this run.
+	} else {
+		j.reserved++
+	}
+	r.turn = true
+	j.runLock.Unlock()
+
+	return nil
+}
+
+// releaseTurn gives up the slot awaitTurn reserved for r if r did not start.
+func (j *jobMetadata) releaseTurn(r *scheduledJob) {
+	j.runLock.Lock()
+	defer j.runLock.Unlock()
+
+	if r.turn {
+		r.turn = false
+		j.reserved--
+		j.handOffLocked()
+	}
+}
+
+// waitForTurn must be called with runLock held. If it returns an error,
+// runLock has been released.
+func (j *jobMetadata) waitForTurn(ctx context.Context, deadline time.Time) error {
+	if len(j.waiting) >= j.jobConfig.queueDepth() {
+		j.runLock.Unlock()
+		return ErrQueueFull{}
+	}
+
+	turn := make(chan struct{})
+	j.waiting = append(j.waiting, turn)
+	j.runLock.Unlock()
+
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case <-turn:
+		j.runLock.Lock()
+		return nil
+	case <-expired:
+		err = ErrPastStartingDeadline{}
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+	}
+
+	j.runLock.Lock()
+	defer j.runLock.Unlock()
+	select {
+	case <-turn:
+		// The slot was handed to us while we were giving up, so pass it on.
+		j.reserved--
+		j.handOffLocked()
+	default:
+		for i, w := range j.waiting {
+			if w == turn {
+				j.waiting = append(j.waiting[:i], j.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return err
+}
+
+// handOffLocked gives the free slot to the oldest queued run, if any.
+func (j *jobMetadata) handOffLocked() {
+	if len(j.active) > 0 || j.reserved > 0 || len(j.waiting) == 0 {
+		return
+	}
+
+	next := j.waiting[0]
+	j.waiting = j.waiting[1:]
+	j.reserved++
+	close(next)
+}
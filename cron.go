@@ -1,11 +1,14 @@
 package cronroutine
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 const (
@@ -19,46 +22,139 @@ type Cron struct {
 	DayOfMonth []int
 	Month      []int
 	DayOfWeek  []int
+
+	// Location is the time zone the schedule is evaluated in. If it is nil,
+	// the schedule is evaluated in UTC.
+	Location *time.Location
 }
 
+// cronFields are the names of the fields of a cron expression, in order.
+var cronFields = [...]string{"minute", "hour", "day of month", "month", "day of week"}
+
+// ParseError is returned when a cron expression can not be parsed.
+type ParseError struct {
+	// Field is the name of the field that could not be parsed, such as
+	// "minute" or "day of week". It is empty if the expression does not have
+	// five fields.
+	Field string
+
+	// Offset is the byte offset in the expression of the field that could
+	// not be parsed, or of the first extra field.
+	Offset int
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Field == "" {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("failed to parse %s: %v", e.Field, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// cronMacros are the schedules that can be given by name instead of fields.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression with five fields, or one of the macros
+// such as @daily.
 func ParseCron(cronConfig string) (*Cron, error) {
-	fields := strings.Fields(cronConfig)
-	if len(fields) != 5 {
-		return nil, fmt.Errorf("given %d but need 5 fields for valid cron config: %s", len(fields), cronConfig)
+	if trimmed := strings.TrimSpace(cronConfig); strings.HasPrefix(trimmed, "@") {
+		expanded, ok := cronMacros[trimmed]
+		if !ok {
+			return nil, &ParseError{
+				Offset: strings.Index(cronConfig, "@"),
+				Err:    fmt.Errorf("unknown macro %s", trimmed),
+			}
+		}
+		return ParseCron(expanded)
 	}
 
-	return NewCron(fields[0], fields[1], fields[2], fields[3], fields[4])
+	fields, offsets := splitFields(cronConfig)
+	if len(fields) != len(cronFields) {
+		offset := len(cronConfig)
+		if len(fields) > len(cronFields) {
+			offset = offsets[len(cronFields)]
+		}
+		return nil, &ParseError{
+			Offset: offset,
+			Err:    fmt.Errorf("given %d but need 5 fields for valid cron config: %s", len(fields), cronConfig),
+		}
+	}
+
+	c, err := NewCron(fields[0], fields[1], fields[2], fields[3], fields[4])
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.Offset = offsets[slices.Index(cronFields[:], parseErr.Field)]
+	}
+
+	return c, err
+}
+
+// splitFields splits s around runs of white space like strings.Fields, and
+// also returns the byte offset of each field.
+func splitFields(s string) ([]string, []int) {
+	fields := []string{}
+	offsets := []int{}
+	start := -1
+	for i, r := range s + " " {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				fields = append(fields, s[start:i])
+				offsets = append(offsets, start)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+
+	return fields, offsets
 }
 
 func NewCron(minute, hour, dayOfMonth, month, dayOfWeek string) (*Cron, error) {
 	c := &Cron{}
 	m, err := parseField(minute, 0, 59)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse minute: %w", err)
+		return nil, &ParseError{Field: "minute", Err: err}
 	}
 	c.Minute = m
 
 	h, err := parseField(hour, 0, 23)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse hour: %w", err)
+		return nil, &ParseError{Field: "hour", Err: err}
 	}
 	c.Hour = h
 
 	dm, err := parseField(dayOfMonth, 1, 31)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse day of month: %w", err)
+		return nil, &ParseError{Field: "day of month", Err: err}
 	}
 	c.DayOfMonth = dm
 
-	mo, err := parseField(month, 1, 12)
+	mo, err := parseField(replaceNames(month, monthNames, 1), 1, 12)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse month: %w", err)
+		return nil, &ParseError{Field: "month", Err: err}
 	}
 	c.Month = mo
 
-	dw, err := parseField(dayOfWeek, 0, 6)
+	// Both 0 and 7 are Sunday.
+	dw, err := parseField(replaceNames(dayOfWeek, weekdayNames, 0), 0, 7)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse day of week: %w", err)
+		return nil, &ParseError{Field: "day of week", Err: err}
+	}
+	if dw[len(dw)-1] == 7 {
+		dw = sortUnique(append(dw[:len(dw)-1], 0))
 	}
 	c.DayOfWeek = dw
 
@@ -72,6 +168,24 @@ func NewCron(minute, hour, dayOfMonth, month, dayOfWeek string) (*Cron, error) {
 	return c, nil
 }
 
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	namePattern  = regexp.MustCompile(`[A-Za-z]+`)
+)
+
+// replaceNames replaces the names of months or weekdays in field with their
+// numbers, the first name being first. Other words are left for parseField to
+// reject.
+func replaceNames(field string, names []string, first int) string {
+	return namePattern.ReplaceAllStringFunc(field, func(name string) string {
+		if i := slices.Index(names, strings.ToLower(name)); i >= 0 {
+			return strconv.Itoa(first + i)
+		}
+		return name
+	})
+}
+
 func parseField(field string, minValue int, maxValue int) ([]int, error) {
 	if field == "*" {
 		return sliceWithStep(minValue, maxValue, 1), nil
@@ -95,6 +209,15 @@ func parseNumber(value string, minValue int, maxValue int) ([]int, error) {
 		return sliceWithStep(minValue, maxValue, 1), nil
 	}
 
+	// */n steps through the whole range.
+	if step, ok := strings.CutPrefix(value, "*/"); ok {
+		ret, err := parseSlashRange(fmt.Sprintf("%d-%d", minValue, maxValue), step, minValue, maxValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse slash %q: %w", value, err)
+		}
+		return ret, nil
+	}
+
 	if strings.Contains(value, "/") {
 		ret, err := parseSlash(value, minValue, maxValue)
 		if err != nil {
@@ -229,28 +352,57 @@ func (c *Cron) Next() time.Time {
 	return c.next(time.Now().UTC())
 }
 
-func (c *Cron) next(t time.Time) time.Time {
-	ct := newCTime(t)
+// NextAfter returns the first time after t that the schedule fires, or the
+// zero time if it never does.
+func (c *Cron) NextAfter(t time.Time) time.Time {
+	return c.next(t)
+}
 
-	ct.minute = getFirstElementGreaterThan(c.Minute, ct.minute)
+// maxNextSearch is how far ahead next looks for a fire time. Schedules that
+// only fire on February 29th can go up to eight years without firing.
+const maxNextSearch = 10
 
-	if !ct.time().After(t) {
-		ct.hour = getFirstElementGreaterThan(c.Hour, ct.hour)
+// next returns the first time after t that the schedule fires, or the zero
+// time if it never fires, for example on February 30th. Each field that does
+// not match moves t to the start of the next month, day, hour or minute.
+func (c *Cron) next(t time.Time) time.Time {
+	loc := c.location()
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxNextSearch, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !slices.Contains(c.Month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, second, nanosecond, loc)
+		case !c.firesOnDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, second, nanosecond, loc)
+		case !slices.Contains(c.Hour, t.Hour()):
+			// Adding minutes rather than building the time with time.Date
+			// keeps moving forward when clocks are turned back.
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case !slices.Contains(c.Minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
 	}
 
-	if !ct.time().After(t) {
-		ct.dayOfMonth = nextDay(c.DayOfWeek, c.DayOfMonth, ct.dayOfWeek, ct.dayOfMonth)
-	}
+	return time.Time{}
+}
 
-	if !ct.time().After(t) {
-		ct.month = getFirstElementGreaterThan(c.Month, ct.month)
-	}
+// firesOnDay reports whether the schedule fires on the day of t. When both the
+// day of month and the day of week are restricted, either may match.
+func (c *Cron) firesOnDay(t time.Time) bool {
+	return (c.DayOfMonth != nil && slices.Contains(c.DayOfMonth, t.Day())) ||
+		(c.DayOfWeek != nil && slices.Contains(c.DayOfWeek, int(t.Weekday())))
+}
 
-	if !ct.time().After(t) {
-		ct.year++
+func (c *Cron) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
 	}
 
-	return ct.time()
+	return c.Location
 }
 
 func (c *Cron) NextFor(t time.Duration) []time.Time {
@@ -259,32 +411,23 @@ func (c *Cron) NextFor(t time.Duration) []time.Time {
 
 func (c *Cron) nextFor(start time.Time, t time.Duration) []time.Time {
 	times := []time.Time{}
-	for next := c.next(start); next.Before(start.UTC().Add(t)); next = c.next(next) {
+	for next := c.next(start); !next.IsZero() && next.Before(start.UTC().Add(t)); next = c.next(next) {
 		times = append(times, next)
 	}
 
 	return times
 }
 
-func nextDay(cronDayOfWeek []int, cronDayOfMonth []int, currentDayOfWeek int, currentDayOfMonth int) int {
-	// 0 1 * * *
-	if cronDayOfMonth == nil && cronDayOfWeek == nil {
-		return currentDayOfMonth + 1
-	}
-
-	o := []int{currentDayOfMonth}
-	// 0 1 1 * *
-	if cronDayOfMonth != nil {
-		o = append(o, getFirstElementGreaterThan(cronDayOfMonth, currentDayOfMonth))
-	}
+// NextN returns the next n times the schedule fires.
+func (c *Cron) NextN(n int) []time.Time {
+	return c.nextN(time.Now().UTC(), n)
+}
 
-	// 0 1 * * 1
-	if cronDayOfWeek != nil {
-		daysUntilJob := (getFirstElementGreaterThan(cronDayOfWeek, currentDayOfWeek) - currentDayOfWeek + 7) % 7
-		o = append(o, (currentDayOfMonth+daysUntilJob)%31)
+func (c *Cron) nextN(start time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, max(n, 0))
+	for next := c.next(start); !next.IsZero() && len(times) < n; next = c.next(next) {
+		times = append(times, next)
 	}
 
-	slices.Sort(o)
-
-	return o[(slices.Index(o, currentDayOfMonth)+1)%len(o)]
+	return times
 }
@@ -0,0 +1,138 @@
+package cronroutine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describe returns a description of the schedule in English, such as
+// "At 09:30, on Monday through Friday".
+func (c *Cron) Describe() string {
+	parts := []string{c.describeTime()}
+
+	days := []string{}
+	if c.DayOfMonth != nil && !isFullField(c.DayOfMonth, 1, 31) {
+		days = append(days, "on "+describeField(c.DayOfMonth, 1, 31, "day", nil)+" of the month")
+	}
+	if c.DayOfWeek != nil && !isFullField(c.DayOfWeek, 0, 6) {
+		days = append(days, "on "+describeField(c.DayOfWeek, 0, 6, "day", weekdayName))
+	}
+	if len(days) > 0 {
+		// When both are restricted, the job runs on days that match either.
+		parts = append(parts, strings.Join(days, " or "))
+	}
+
+	if !isFullField(c.Month, 1, 12) {
+		parts = append(parts, "in "+describeField(c.Month, 1, 12, "month", monthName))
+	}
+
+	desc := strings.Join(parts, ", ")
+	if c.Location != nil && c.Location != time.UTC {
+		desc += " (" + c.Location.String() + ")"
+	}
+
+	return desc
+}
+
+func (c *Cron) describeTime() string {
+	everyHour := isFullField(c.Hour, 0, 23)
+	if len(c.Minute) == 1 && len(c.Hour) <= 4 && !everyHour {
+		times := make([]string, 0, len(c.Hour))
+		for _, hour := range c.Hour {
+			times = append(times, fmt.Sprintf("%02d:%02d", hour, c.Minute[0]))
+		}
+		return "At " + joinList(times)
+	}
+
+	minutes := describeField(c.Minute, 0, 59, "minute", nil)
+	hours := describeField(c.Hour, 0, 23, "hour", nil)
+	if strings.HasPrefix(minutes, "every") {
+		if everyHour {
+			return capitalize(minutes)
+		}
+		return capitalize(minutes) + " during " + hours
+	}
+
+	if everyHour {
+		return "At " + minutes + " past every hour"
+	}
+
+	return "At " + minutes + " past " + hours
+}
+
+// describeField describes the values of a field. Values are written as numbers
+// after the unit, such as "hours 9 and 17", or with name if it is not nil.
+func describeField(values []int, minValue, maxValue int, unit string, name func(int) string) string {
+	if isFullField(values, minValue, maxValue) {
+		return "every " + unit
+	}
+
+	numeric := name == nil
+	if numeric {
+		name = strconv.Itoa
+	}
+	withUnit := func(s string, count int) string {
+		if !numeric {
+			return s
+		}
+		if count > 1 {
+			return unit + "s " + s
+		}
+		return unit + " " + s
+	}
+
+	first, last := values[0], values[len(values)-1]
+	step := 0
+	if len(values) > 1 {
+		step = values[1] - values[0]
+		for i := 2; i < len(values); i++ {
+			if values[i]-values[i-1] != step {
+				step = 0
+				break
+			}
+		}
+	}
+
+	switch {
+	case step > 1 && len(values) > 2 && first-minValue < step && last+step > maxValue:
+		if first == minValue {
+			return fmt.Sprintf("every %d %ss", step, unit)
+		}
+		return fmt.Sprintf("every %d %ss starting at %s", step, unit, withUnit(name(first), 1))
+	case step > 1 && len(values) > 2:
+		return fmt.Sprintf("every %d %ss from %s through %s", step, unit, name(first), name(last))
+	case step == 1 && len(values) > 2:
+		return withUnit(name(first)+" through "+name(last), len(values))
+	}
+
+	names := make([]string, 0, len(values))
+	for _, v := range values {
+		names = append(names, name(v))
+	}
+	return withUnit(joinList(names), len(values))
+}
+
+func isFullField(values []int, minValue, maxValue int) bool {
+	return len(values) == maxValue-minValue+1
+}
+
+func joinList(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func weekdayName(v int) string { return time.Weekday(v).String() }
+func monthName(v int) string   { return time.Month(v).String() }
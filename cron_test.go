@@ -121,6 +121,36 @@ var (
 			currentTime: time.Date(2024, time.March, 29, 10, 36, second, nanosecond, time.UTC),
 			expected:    time.Date(2024, time.March, 29, 11, 0, second, nanosecond, time.UTC),
 		},
+		{
+			name:        "later hour the same day (30 9 * * *)",
+			Minute:      []int{30},
+			Hour:        []int{9},
+			DayOfMonth:  allDaysInMonth,
+			Month:       allMonths,
+			DayOfWeek:   allDaysOfWeek,
+			currentTime: time.Date(2024, time.March, 29, 7, 0, second, nanosecond, time.UTC),
+			expected:    time.Date(2024, time.March, 29, 9, 30, second, nanosecond, time.UTC),
+		},
+		{
+			name:        "between hours that fire (30 9-17/2 * * *)",
+			Minute:      []int{30},
+			Hour:        []int{9, 11, 13, 15, 17},
+			DayOfMonth:  allDaysInMonth,
+			Month:       allMonths,
+			DayOfWeek:   allDaysOfWeek,
+			currentTime: time.Date(2024, time.March, 29, 14, 0, second, nanosecond, time.UTC),
+			expected:    time.Date(2024, time.March, 29, 15, 30, second, nanosecond, time.UTC),
+		},
+		{
+			name:        "never fires (0 0 30 2 *)",
+			Minute:      []int{0},
+			Hour:        []int{0},
+			DayOfMonth:  []int{30},
+			Month:       []int{2},
+			DayOfWeek:   nil,
+			currentTime: time.Date(2024, time.March, 29, 14, 0, second, nanosecond, time.UTC),
+			expected:    time.Time{},
+		},
 	}
 
 	allMinutes = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30,
@@ -192,6 +222,85 @@ func TestCron_NextFor(t *testing.T) {
 	}
 }
 
+func TestCron_NextN(t *testing.T) {
+	cron, err := ParseCron("30 9-17/2 * * 1-5")
+	assert.NoError(t, err)
+
+	start := time.Date(2024, time.March, 29, 10, 36, second, nanosecond, time.UTC)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, time.March, 29, 11, 30, second, nanosecond, time.UTC),
+		time.Date(2024, time.March, 29, 13, 30, second, nanosecond, time.UTC),
+		time.Date(2024, time.March, 29, 15, 30, second, nanosecond, time.UTC),
+	}, cron.nextN(start, 3))
+	assert.Empty(t, cron.nextN(start, 0))
+}
+
+func TestCron_Describe(t *testing.T) {
+	tests := []struct {
+		cronConfig string
+		expected   string
+	}{
+		{"* * * * *", "Every minute"},
+		{"0/15 * * * *", "Every 15 minutes"},
+		{"5/15 * * * *", "Every 15 minutes starting at minute 5"},
+		{"* 9-17 * * *", "Every minute during hours 9 through 17"},
+		{"30 9 * * 1-5", "At 09:30, on Monday through Friday"},
+		{"0 9,17 * * *", "At 09:00 and 17:00"},
+		{"0 0 1 1 *", "At 00:00, on day 1 of the month, in January"},
+		{"5 * * * 0,6", "At minute 5 past every hour, on Sunday and Saturday"},
+		{"0,30 9-17 * * *", "At minutes 0 and 30 past hours 9 through 17"},
+		{"30 9-17/2 * * 1-5", "At minute 30 past every 2 hours from 9 through 17, on Monday through Friday"},
+		{"0 12 1,15 * 0", "At 12:00, on days 1 and 15 of the month or on Sunday"},
+		{"0 0 1 1/3 *", "At 00:00, on day 1 of the month, in every 3 months"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cronConfig, func(t *testing.T) {
+			cron, err := ParseCron(tt.cronConfig)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cron.Describe())
+		})
+	}
+}
+
+func TestCron_Location(t *testing.T) {
+	cron, err := ParseCron("30 9 * * *")
+	assert.NoError(t, err)
+	cron.Location = time.FixedZone("EST", -5*60*60)
+
+	start := time.Date(2024, time.March, 9, 12, 0, second, nanosecond, time.UTC)
+	next := cron.nextN(start, 2)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, time.March, 9, 14, 30, second, nanosecond, time.UTC),
+		time.Date(2024, time.March, 10, 14, 30, second, nanosecond, time.UTC),
+	}, []time.Time{next[0].UTC(), next[1].UTC()})
+	assert.Equal(t, cron.Location, next[0].Location())
+	assert.Equal(t, "At 09:30 (EST)", cron.Describe())
+}
+
+func TestParseCron_errorOffset(t *testing.T) {
+	tests := []struct {
+		cronConfig string
+		field      string
+		offset     int
+	}{
+		{"0 0 2 *", "", 7},
+		{"0 0  2 1 1 0", "", 11},
+		{"0  25 * * *", "hour", 3},
+		{" 0 0 * hello *", "month", 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cronConfig, func(t *testing.T) {
+			_, err := ParseCron(tt.cronConfig)
+			var parseErr *ParseError
+			assert.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, tt.field, parseErr.Field)
+			assert.Equal(t, tt.offset, parseErr.Offset)
+		})
+	}
+}
+
 func TestParseCron(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -265,6 +374,56 @@ func TestParseCron(t *testing.T) {
 				DayOfWeek:  []int{1, 2, 3, 4, 5},
 			},
 		},
+		{
+			name:       "macro",
+			cronConfig: "@daily",
+			expected: &Cron{
+				Minute:     []int{0},
+				Hour:       []int{0},
+				DayOfMonth: allDaysInMonth,
+				Month:      allMonths,
+				DayOfWeek:  allDaysOfWeek,
+			},
+		},
+		{
+			name:       "every twenty minutes",
+			cronConfig: "*/20 * * * *",
+			expected: &Cron{
+				Minute:     []int{0, 20, 40},
+				Hour:       allHours,
+				DayOfMonth: allDaysInMonth,
+				Month:      allMonths,
+				DayOfWeek:  allDaysOfWeek,
+			},
+		},
+		{
+			name:       "names of months and weekdays",
+			cronConfig: "0 12 * jan,Jul MON-fri",
+			expected: &Cron{
+				Minute:     []int{0},
+				Hour:       []int{12},
+				DayOfMonth: nil,
+				Month:      []int{1, 7},
+				DayOfWeek:  []int{1, 2, 3, 4, 5},
+			},
+		},
+		{
+			name:       "seven is Sunday",
+			cronConfig: "0 12 * * 5-7",
+			expected: &Cron{
+				Minute:     []int{0},
+				Hour:       []int{12},
+				DayOfMonth: nil,
+				Month:      allMonths,
+				DayOfWeek:  []int{0, 5, 6},
+			},
+		},
+		{
+			name:       "unknown macro",
+			cronConfig: "@reboot",
+			expected:   nil,
+			errMsg:     "unknown macro @reboot",
+		},
 		{
 			name:       "too few fields",
 			cronConfig: "0 0 2 *",
@@ -587,85 +746,3 @@ func TestParse_parseSlashRange(t *testing.T) {
 		})
 	}
 }
-
-func Test_nextDay(t *testing.T) {
-	type args struct {
-		cronDayOfWeek     []int
-		cronDayOfMonth    []int
-		currentDayOfWeek  int
-		currentDayOfMonth int
-	}
-	tests := []struct {
-		name     string
-		args     args
-		expected int
-	}{
-		{
-			name: "next day is the same day",
-			args: args{
-				cronDayOfWeek:     []int{0, 1, 2, 3, 4, 5, 6},
-				cronDayOfMonth:    []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
-				currentDayOfWeek:  0,
-				currentDayOfMonth: 1,
-			},
-			expected: 2,
-		},
-		{
-			name: "weekdays",
-			args: args{
-				cronDayOfWeek:     []int{1, 2, 3, 4, 5},
-				cronDayOfMonth:    nil,
-				currentDayOfWeek:  0,
-				currentDayOfMonth: 6,
-			},
-			expected: 7,
-		},
-		{
-			name: "everyday",
-			args: args{
-				cronDayOfWeek:     allDaysOfWeek,
-				cronDayOfMonth:    nil,
-				currentDayOfWeek:  0,
-				currentDayOfMonth: 6,
-			},
-			expected: 7,
-		},
-		{
-			name: "sundays and the first of the month",
-			args: args{
-				cronDayOfWeek:     []int{int(time.Sunday)},
-				cronDayOfMonth:    []int{1},
-				currentDayOfWeek:  int(time.Friday),
-				currentDayOfMonth: 6,
-			},
-			expected: 8,
-		},
-		{
-			name: "first of the month",
-			args: args{
-				cronDayOfWeek:     nil,
-				cronDayOfMonth:    []int{1},
-				currentDayOfWeek:  int(time.Friday),
-				currentDayOfMonth: 6,
-			},
-			expected: 1,
-		},
-		{
-			name: "3rd of the month and Saturdays",
-			args: args{
-				cronDayOfWeek:     []int{int(time.Saturday)},
-				cronDayOfMonth:    []int{3},
-				currentDayOfWeek:  int(time.Sunday),
-				currentDayOfMonth: 30,
-			},
-			expected: 3,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			actual := nextDay(tt.args.cronDayOfWeek, tt.args.cronDayOfMonth, tt.args.currentDayOfWeek, tt.args.currentDayOfMonth)
-			assert.Equal(t, tt.expected, actual)
-		})
-	}
-}
@@ -0,0 +1,225 @@
+package cronroutine
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// defaultCrontabShell runs the commands of a crontab that does not set SHELL.
+const defaultCrontabShell = "/bin/sh"
+
+// CrontabError is an error in one line of a crontab.
+type CrontabError struct {
+	Name string
+	Line int
+
+	// Column is the column of the line, counting from 1, at which the field
+	// that could not be parsed starts. It is where the line starts if the
+	// error is not about one field.
+	Column int
+
+	Err error
+}
+
+func (e *CrontabError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Name, e.Line, e.Err)
+}
+
+func (e *CrontabError) Unwrap() error {
+	return e.Err
+}
+
+// ParseCrontab parses a crontab in the format of a user's crontab, as edited
+// by crontab(1), and returns a JobConfig for every command in it. The system
+// crontabs /etc/crontab and /etc/cron.d/*, which have a user field before the
+// command, are not supported. name identifies the crontab, usually by
+// its path. The ID of each job is name and a hash of its schedule and command,
+// as in "crontab:1f0c4a9e", so that it does not change when other lines are
+// added or removed. Lines that are the same get IDs ending in -2, -3 and so on
+// after the first.
+//
+// Blank lines and lines starting with # are ignored. Lines of the form
+// NAME=value set an environment variable for the commands after them; the
+// value may be quoted. SHELL chooses the shell that runs the commands, which
+// is /bin/sh by default. Every other line is a schedule, either five fields
+// or a macro such as @daily, followed by a command. The first % in the
+// command that is not escaped as \% ends it, and the text after it is given
+// to the command as its standard input, with any further % replaced by
+// newlines.
+//
+// The jobs run their command with "SHELL -c COMMAND" through CommandJob, in
+// the environment of the process plus the crontab's variables. Their
+// Fingerprint is a hash of the command, its standard input, the shell and the
+// variables, so that Reconcile updates a job when any of them change.
+//
+// ParseCrontab parses every line before it returns. If any were invalid, the
+// returned error joins a *CrontabError for each of them.
+func ParseCrontab(r io.Reader, name string) ([]JobConfig, error) {
+	var jobs []JobConfig
+	var errs []error
+	var env []string
+	shell := defaultCrontabShell
+	ids := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if key, value, ok := parseCrontabEnv(text); ok {
+			if key == "SHELL" {
+				shell = value
+			}
+			env = append(env, key+"="+value)
+			continue
+		}
+
+		job, err := parseCrontabJob(name, text, shell, env)
+		if err != nil {
+			column := len(raw) - len(strings.TrimLeftFunc(raw, unicode.IsSpace)) + 1
+			var parseErr *ParseError
+			if errors.As(err, &parseErr) {
+				column += parseErr.Offset
+			}
+			errs = append(errs, &CrontabError{Name: name, Line: line, Column: column, Err: err})
+			continue
+		}
+		if ids[job.ID]++; ids[job.ID] > 1 {
+			job.ID = fmt.Sprintf("%s-%d", job.ID, ids[job.ID])
+		}
+		jobs = append(jobs, job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read crontab %s: %w", name, err)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return jobs, nil
+}
+
+// parseCrontabEnv parses a line that sets an environment variable. It returns
+// false if line is not one.
+func parseCrontabEnv(line string) (string, string, bool) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", false
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", "", false
+	}
+	for i, r := range key {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || r < '0' || r > '9') {
+			return "", "", false
+		}
+	}
+
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+
+	return key, value, true
+}
+
+func parseCrontabJob(name, line, shell string, env []string) (JobConfig, error) {
+	count := 5
+	if strings.HasPrefix(line, "@") {
+		count = 1
+	}
+
+	// Parse the schedule as it is written in the line, so that the offset of
+	// a ParseError is also the offset in the line.
+	fields, command := cutFields(line, count)
+	if _, err := ParseCron(strings.TrimRight(line[:len(line)-len(command)], " \t")); err != nil {
+		return JobConfig{}, err
+	}
+	if command == "" {
+		return JobConfig{}, fmt.Errorf("need a schedule and a command: %s", line)
+	}
+
+	schedule := strings.Join(fields, " ")
+	id := crontabJobID(name, schedule, command)
+	command, stdin := splitCrontabCommand(command)
+	cfg := CommandConfig{
+		Args:  []string{shell, "-c", command},
+		Env:   slices.Clone(env),
+		Stdin: stdin,
+	}
+	return JobConfig{
+		ID:          id,
+		Schedule:    schedule,
+		Func:        CommandJob(cfg),
+		Fingerprint: commandFingerprint(cfg),
+	}, nil
+}
+
+// commandFingerprint hashes the parts of cfg a crontab sets.
+func commandFingerprint(cfg CommandConfig) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%q %q %q", cfg.Args, cfg.Env, cfg.Stdin)
+
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// crontabJobID returns the ID of the job that runs command on schedule in
+// the crontab called name.
+func crontabJobID(name, schedule, command string) string {
+	h := fnv.New32a()
+	h.Write([]byte(schedule))
+	h.Write([]byte{0})
+	h.Write([]byte(command))
+
+	return fmt.Sprintf("%s:%08x", name, h.Sum32())
+}
+
+// cutFields returns the first n fields of s, separated by spaces or tabs, and
+// the rest of s after them with leading space removed.
+func cutFields(s string, n int) ([]string, string) {
+	var fields []string
+	rest := strings.TrimLeft(s, " \t")
+	for len(fields) < n && rest != "" {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+
+	return fields, rest
+}
+
+// splitCrontabCommand splits the command of a crontab line at its first
+// unescaped %, and returns the command and the standard input after it.
+func splitCrontabCommand(s string) (string, string) {
+	var command strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '%':
+			command.WriteByte('%')
+			i++
+		case s[i] == '%':
+			stdin := strings.ReplaceAll(s[i+1:], "%", "\n")
+			return command.String(), stdin + "\n"
+		default:
+			command.WriteByte(s[i])
+		}
+	}
+
+	return command.String(), ""
+}
@@ -0,0 +1,107 @@
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCrontab(t *testing.T) {
+	t.Parallel()
+	crontab := `# m h dom mon dow command
+SHELL=/bin/sh
+GREETING = "hello world"
+
+*/5 * * * *	echo "$GREETING" from $SHELL
+@daily cat%first%second
+0 0 * * * echo 100\%; exit 3
+`
+
+	jobs, err := ParseCrontab(strings.NewReader(crontab), "crontab")
+	assert.NoError(t, err)
+	if !assert.Len(t, jobs, 3) {
+		return
+	}
+	assert.Equal(t, "*/5 * * * *", jobs[0].Schedule)
+	assert.Equal(t, "@daily", jobs[1].Schedule)
+
+	scheduler := newTestScheduler(1)
+	for _, job := range jobs {
+		assert.NoError(t, scheduler.AddJob(job))
+	}
+
+	tests := []struct {
+		id     string
+		result string
+		errMsg string
+	}{
+		{id: jobs[0].ID, result: "hello world from /bin/sh\n"},
+		{id: jobs[1].ID, result: "first\nsecond\n"},
+		{id: jobs[2].ID, result: "100%\n", errMsg: "command exited with status 3"},
+	}
+	for _, tt := range tests {
+		err := scheduler.TriggerJob(context.Background(), tt.id)
+		if tt.errMsg != "" {
+			assert.EqualError(t, err, tt.errMsg)
+		} else {
+			assert.NoError(t, err)
+		}
+
+		job, err := scheduler.GetJob(tt.id)
+		assert.NoError(t, err)
+		if history := job.History(); assert.Len(t, history, 1) {
+			assert.Equal(t, tt.result, history[0].Result())
+		}
+	}
+}
+
+func TestParseCrontab_ids(t *testing.T) {
+	t.Parallel()
+	jobs, err := ParseCrontab(strings.NewReader(`0 0 * * * /bin/true
+@daily /bin/true
+0 0 * * * /bin/true
+`), "crontab")
+	assert.NoError(t, err)
+	ids := []string{}
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	assert.Regexp(t, `^crontab:[0-9a-f]{8}$`, ids[0])
+	assert.Equal(t, []string{ids[0], ids[1], ids[0] + "-2"}, ids)
+	assert.NotEqual(t, ids[0], ids[1])
+
+	// Adding lines and spacing the fields differently does not change the
+	// IDs.
+	jobs, err = ParseCrontab(strings.NewReader(`# nightly
+0  0 * * *	/bin/true
+MAILTO=""
+@daily /bin/true
+`), "crontab")
+	assert.NoError(t, err)
+	if assert.Len(t, jobs, 2) {
+		assert.Equal(t, ids[:2], []string{jobs[0].ID, jobs[1].ID})
+	}
+}
+
+func TestParseCrontab_errors(t *testing.T) {
+	t.Parallel()
+	crontab := `0 0 * * * /bin/true
+0 24 * * * /bin/true
+@reboot /bin/true
+0 0 * * *
+`
+
+	jobs, err := ParseCrontab(strings.NewReader(crontab), "crontab")
+	assert.Nil(t, jobs)
+	assert.EqualError(t, err, "crontab:2: failed to parse hour: failed to parse number: value 24 is not in range 0-23\n"+
+		"crontab:3: unknown macro @reboot\n"+
+		"crontab:4: need a schedule and a command: 0 0 * * *")
+
+	var crontabErr *CrontabError
+	assert.True(t, errors.As(err, &crontabErr))
+	assert.Equal(t, 2, crontabErr.Line)
+	assert.Equal(t, 3, crontabErr.Column)
+}
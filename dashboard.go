@@ -0,0 +1,121 @@
+package cronroutine
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"pathEscape": url.PathEscape,
+	"formatTime": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05 MST") },
+}).Parse(dashboardHTML))
+
+// The dashboard shows up to dashboardNextRuns fire times of each job within
+// dashboardNextFor, or only the next one if none are that soon. Jobs that only
+// run after their dependencies have none.
+const (
+	dashboardNextRuns = 5
+	dashboardNextFor  = 24 * time.Hour
+)
+
+// DashboardHandler is an http.Handler that serves an HTML page showing the
+// jobs of a Scheduler, with their schedules, next fire times and recent runs,
+// and buttons to trigger, pause and resume them. The page has no external
+// assets. The buttons use an AdminHandler served under /api/, which shares the
+// dashboard's authorization; viewing the page is authorized as AdminRead.
+//
+// The page uses relative links, so mount it on a path that ends in a slash,
+// for example:
+//
+//	mux.Handle("/cron/", http.StripPrefix("/cron", cronroutine.NewDashboardHandler(s, nil)))
+type DashboardHandler struct {
+	scheduler *Scheduler
+	admin     *AdminHandler
+}
+
+type dashboardJob struct {
+	ID          string
+	Schedule    string
+	Description string
+	Paused      bool
+	NextRuns    []time.Time
+	History     []*History
+}
+
+// NewDashboardHandler returns a DashboardHandler for s. cfg may be nil.
+func NewDashboardHandler(s *Scheduler, cfg *AdminHandlerConfig) *DashboardHandler {
+	return &DashboardHandler{
+		scheduler: s,
+		admin:     NewAdminHandler(s, cfg),
+	}
+}
+
+func (h *DashboardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		http.StripPrefix("/api", h.admin).ServeHTTP(w, r)
+		return
+	}
+
+	if r.URL.Path != "/" && r.URL.Path != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.admin.authorize != nil {
+		if err := h.admin.authorize(r, AdminRead, ""); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := dashboardTemplate.Execute(&buf, h.jobs()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+func (h *DashboardHandler) jobs() []*dashboardJob {
+	jobs := h.scheduler.ListJobs()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID() < jobs[j].ID() })
+
+	ret := make([]*dashboardJob, 0, len(jobs))
+	for _, j := range jobs {
+		next := j.NextFor(dashboardNextFor)
+		if len(next) > dashboardNextRuns {
+			next = next[:dashboardNextRuns]
+		} else if len(next) == 0 && !j.NextRun().IsZero() {
+			next = []time.Time{j.NextRun()}
+		}
+
+		ret = append(ret, &dashboardJob{
+			ID:          j.ID(),
+			Schedule:    j.Schedule(),
+			Description: j.Description(),
+			Paused:      j.Paused(),
+			NextRuns:    next,
+			History:     j.History(),
+		})
+	}
+
+	return ret
+}
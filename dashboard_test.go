@@ -0,0 +1,79 @@
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	err := scheduler.AddJob(JobConfig{
+		ID:                "test/<0>",
+		Schedule:          "30 9 * * 1-5",
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Func:              func(ctx context.Context) error { return errors.New("failed") },
+	})
+	assert.NoError(t, err)
+	assert.Error(t, scheduler.TriggerJob(context.Background(), "test/<0>"))
+
+	mux := http.NewServeMux()
+	mux.Handle("/cron/", http.StripPrefix("/cron", NewDashboardHandler(scheduler, &AdminHandlerConfig{
+		Authorize: func(r *http.Request, action AdminAction, jobID string) error {
+			if r.Header.Get("X-User") != "admin" {
+				return errors.New("only admins can see jobs")
+			}
+			return nil
+		},
+	})))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(path string, user string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		assert.NoError(t, err)
+		req.Header.Set("X-User", user)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp, string(b)
+	}
+
+	resp, _ := get("/cron/", "someone")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, body := get("/cron/", "admin")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, "test/&lt;0&gt;")
+	assert.Contains(t, body, "<code>30 9 * * 1-5</code>")
+	assert.Contains(t, body, "At 09:30, on Monday through Friday")
+	assert.Contains(t, body, `<div class="run Failed"`)
+	assert.Contains(t, body, `data-action="api/jobs/test%2F%3C0%3E/trigger"`)
+	assert.Contains(t, body, `data-action="api/jobs/test%2F%3C0%3E/pause"`)
+	assert.NotContains(t, body, "http://", "the page has no external assets")
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/cron/api/jobs/test%2F%3C0%3E/pause", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-User", "admin")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, body = get("/cron/", "admin")
+	assert.Contains(t, body, `<span class="paused">paused</span>`)
+	assert.Contains(t, body, `data-action="api/jobs/test%2F%3C0%3E/resume"`)
+
+	resp, _ = get("/cron/other", "admin")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
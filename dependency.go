@@ -0,0 +1,229 @@
+package cronroutine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DependencyCondition determines which runs of an upstream job make the jobs
+// that depend on it run.
+type DependencyCondition string
+
+const (
+	// DependencySuccess runs the dependent job after a run of the upstream
+	// job succeeds. It is the default.
+	DependencySuccess DependencyCondition = "Success"
+
+	// DependencyAlways runs the dependent job after every run of the
+	// upstream job, whatever its outcome. Runs that were skipped do not
+	// count, since they never started.
+	DependencyAlways DependencyCondition = "Always"
+)
+
+// Dependency makes a job run after a run of another job finishes.
+type Dependency struct {
+	// JobID is the ID of the upstream job. It does not have to exist yet;
+	// until it does, the dependency is never satisfied.
+	JobID string
+
+	Condition DependencyCondition
+}
+
+func (d Dependency) condition() DependencyCondition {
+	if d.Condition == "" {
+		return DependencySuccess
+	}
+
+	return d.Condition
+}
+
+// satisfiedBy reports whether a run of the upstream job that finished with err
+// satisfies the dependency.
+func (d Dependency) satisfiedBy(err error) bool {
+	switch statusOf(err) {
+	case RunSkipped:
+		return false
+	case RunSucceeded:
+		return true
+	default:
+		return d.condition() == DependencyAlways
+	}
+}
+
+// ErrDependencyCycle is returned when adding or updating jobs would make their
+// dependencies form a cycle.
+type ErrDependencyCycle struct {
+	// Cycle lists the IDs of the jobs in the cycle, starting and ending
+	// with the same job.
+	Cycle []string
+}
+
+func (e ErrDependencyCycle) Error() string {
+	return fmt.Sprintf("job dependencies form a cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// describeDependencies describes deps in English, as in "after export
+// succeeds and cleanup finishes".
+func describeDependencies(deps []Dependency) string {
+	if len(deps) == 0 {
+		return ""
+	}
+
+	items := make([]string, 0, len(deps))
+	for _, d := range deps {
+		if d.condition() == DependencyAlways {
+			items = append(items, d.JobID+" finishes")
+		} else {
+			items = append(items, d.JobID+" succeeds")
+		}
+	}
+
+	return "after " + joinList(items)
+}
+
+func validateDependencies(deps []Dependency) error {
+	for _, d := range deps {
+		if d.JobID == "" {
+			return errors.New("dependency has no job ID")
+		}
+
+		switch d.Condition {
+		case "", DependencySuccess, DependencyAlways:
+		default:
+			return fmt.Errorf("unknown dependency condition %q", d.Condition)
+		}
+	}
+
+	return nil
+}
+
+// findCycle returns a cycle in the dependency graph reachable from the jobs in
+// ids, or nil if there is none. deps returns the dependencies of a job, and
+// nil for jobs that do not exist.
+func findCycle(ids []string, deps func(id string) []Dependency) []string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	marks := map[string]int{}
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		switch marks[id] {
+		case visiting:
+			start := slices.Index(path, id)
+			return append(slices.Clone(path[start:]), id)
+		case done:
+			return nil
+		}
+
+		marks[id] = visiting
+		path = append(path, id)
+		for _, d := range deps(id) {
+			if cycle := visit(d.JobID); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		marks[id] = done
+
+		return nil
+	}
+
+	for _, id := range ids {
+		if cycle := visit(id); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// checkDependencies returns ErrDependencyCycle if job would make the
+// dependencies of the scheduler's jobs form a cycle. The caller must hold
+// jobsLock.
+func (s *Scheduler) checkDependencies(job JobConfig) error {
+	cycle := findCycle([]string{job.ID}, func(id string) []Dependency {
+		if id == job.ID {
+			return job.DependsOn
+		}
+		if existing, ok := s.jobs[id]; ok {
+			return existing.jobConfig.DependsOn
+		}
+		return nil
+	})
+	if cycle != nil {
+		return ErrDependencyCycle{Cycle: cycle}
+	}
+
+	return nil
+}
+
+// upstreamFinished is called when a run of upstream has finished all of its
+// attempts with err. It starts the runs of the jobs whose dependencies that
+// completes.
+func (s *Scheduler) upstreamFinished(upstream *scheduledJob, err error) {
+	s.jobsLock.RLock()
+	var runs []*scheduledJob
+	for _, job := range s.jobs {
+		if r := job.dependencyFinished(upstream, err); r != nil {
+			runs = append(runs, r)
+		}
+	}
+	s.jobsLock.RUnlock()
+
+	for _, r := range runs {
+		if r.job.isPaused() {
+			s.logger.Info("job paused, skipping dependent run", "job_id", r.job.ID(), "upstream_run_ids", r.upstream)
+			continue
+		}
+
+		s.events.publish(Event{Type: EventRunScheduled, JobID: r.job.ID(), Run: r.runInfo()})
+		s.submit(r)
+	}
+}
+
+// dependencyFinished records that a run of upstream finished with err. If that
+// satisfies the last of the job's dependencies, it returns a run of the job
+// that links to the upstream runs that satisfied them.
+func (j *jobMetadata) dependencyFinished(upstream *scheduledJob, err error) *scheduledJob {
+	upstreamID := upstream.job.ID()
+	if !slices.ContainsFunc(j.jobConfig.DependsOn, func(d Dependency) bool { return d.JobID == upstreamID }) {
+		return nil
+	}
+
+	j.depsLock.Lock()
+	defer j.depsLock.Unlock()
+
+	for _, d := range j.jobConfig.DependsOn {
+		if d.JobID != upstreamID {
+			continue
+		}
+		if d.satisfiedBy(err) {
+			j.satisfied[upstreamID] = upstream.id
+		} else {
+			delete(j.satisfied, upstreamID)
+		}
+	}
+
+	var upstreamRunIDs []string
+	for _, d := range j.jobConfig.DependsOn {
+		runID, ok := j.satisfied[d.JobID]
+		if !ok {
+			return nil
+		}
+		if !slices.Contains(upstreamRunIDs, runID) {
+			upstreamRunIDs = append(upstreamRunIDs, runID)
+		}
+	}
+	clear(j.satisfied)
+
+	now := time.Now().UTC()
+	r := j.newRun(TriggerDependency, now, now)
+	r.upstream = upstreamRunIDs
+	return r
+}
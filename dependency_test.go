@@ -0,0 +1,149 @@
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_dependencies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		exportErr error
+		ran       []string
+		notRan    []string
+	}{
+		{
+			name:   "upstream succeeds",
+			ran:    []string{"transform", "report", "load"},
+			notRan: []string{},
+		},
+		{
+			name:      "upstream fails",
+			exportErr: errors.New("failed"),
+			ran:       []string{"report"},
+			notRan:    []string{"transform", "load"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			scheduler := newTestScheduler(1)
+			var loads atomic.Int32
+			fn := func(ctx context.Context) error { return nil }
+
+			// export fans out to transform and report, which fan in to
+			// load.
+			jobs := []JobConfig{
+				{ID: "export", Schedule: "0 0 1 1 *", Func: func(ctx context.Context) error { return tt.exportErr }},
+				{ID: "transform", DependsOn: []Dependency{{JobID: "export"}}, Func: fn},
+				{ID: "report", DependsOn: []Dependency{{JobID: "export", Condition: DependencyAlways}}, Func: fn},
+				{
+					ID:        "load",
+					DependsOn: []Dependency{{JobID: "transform"}, {JobID: "report"}},
+					Func: func(ctx context.Context) error {
+						loads.Add(1)
+						return nil
+					},
+				},
+			}
+			for _, job := range jobs {
+				assert.NoError(t, scheduler.AddJob(job))
+			}
+
+			assert.Equal(t, tt.exportErr, scheduler.TriggerJob(context.Background(), "export"))
+			history := func(id string) []*History {
+				job, err := scheduler.GetJob(id)
+				assert.NoError(t, err)
+				return job.History()
+			}
+			exportRun := history("export")[0].RunID()
+
+			for _, id := range tt.ran {
+				assert.Eventually(t, func() bool { return len(history(id)) == 1 }, time.Second, time.Millisecond, id)
+			}
+			time.Sleep(50 * time.Millisecond)
+			for _, id := range tt.notRan {
+				assert.Empty(t, history(id), id)
+			}
+
+			report := history("report")[0]
+			assert.Equal(t, TriggerDependency, report.Trigger())
+			assert.Equal(t, []string{exportRun}, report.UpstreamRunIDs())
+
+			if tt.exportErr == nil {
+				load := history("load")[0]
+				assert.Equal(t, []string{history("transform")[0].RunID(), report.RunID()}, load.UpstreamRunIDs())
+				assert.Equal(t, int32(1), loads.Load())
+
+				// load waits for both of its dependencies again.
+				assert.NoError(t, scheduler.TriggerJob(context.Background(), "transform"))
+				time.Sleep(50 * time.Millisecond)
+				assert.Equal(t, int32(1), loads.Load())
+			}
+		})
+	}
+}
+
+func TestScheduler_dependencyCycles(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	fn := func(ctx context.Context) error { return nil }
+
+	assert.NoError(t, scheduler.AddJob(JobConfig{ID: "a", DependsOn: []Dependency{{JobID: "c"}}, Func: fn}))
+	assert.NoError(t, scheduler.AddJob(JobConfig{ID: "b", DependsOn: []Dependency{{JobID: "a"}}, Func: fn}))
+
+	err := scheduler.AddJob(JobConfig{ID: "c", DependsOn: []Dependency{{JobID: "b"}}, Func: fn})
+	assert.Equal(t, ErrDependencyCycle{Cycle: []string{"c", "b", "a", "c"}}, err)
+	assert.EqualError(t, err, "job dependencies form a cycle: c -> b -> a -> c")
+
+	err = scheduler.UpdateJob(JobConfig{ID: "a", DependsOn: []Dependency{{JobID: "a"}}, Func: fn})
+	assert.Equal(t, ErrDependencyCycle{Cycle: []string{"a", "a"}}, err)
+
+	_, err = scheduler.Reconcile([]JobConfig{
+		{ID: "a", DependsOn: []Dependency{{JobID: "b"}}, Func: fn},
+		{ID: "b", DependsOn: []Dependency{{JobID: "a"}}, Func: fn},
+	})
+	assert.Equal(t, ErrDependencyCycle{Cycle: []string{"a", "b", "a"}}, err)
+
+	err = scheduler.AddJob(JobConfig{ID: "d", DependsOn: []Dependency{{JobID: "a", Condition: "Sometimes"}}, Func: fn})
+	assert.EqualError(t, err, `unknown dependency condition "Sometimes"`)
+
+	err = scheduler.AddJob(JobConfig{ID: "e", Func: fn})
+	assert.ErrorContains(t, err, "failed to parse cron schedule", "jobs without dependencies need a schedule")
+}
+
+func TestJob_Description_dependencies(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	fn := func(ctx context.Context) error { return nil }
+
+	assert.NoError(t, scheduler.AddJob(JobConfig{
+		ID:        "load",
+		DependsOn: []Dependency{{JobID: "transform"}, {JobID: "report", Condition: DependencyAlways}},
+		Func:      fn,
+	}))
+	assert.NoError(t, scheduler.AddJob(JobConfig{
+		ID:        "cleanup",
+		Schedule:  "0 3 * * *",
+		DependsOn: []Dependency{{JobID: "load"}},
+		Func:      fn,
+	}))
+
+	load, err := scheduler.GetJob("load")
+	assert.NoError(t, err)
+	assert.Equal(t, "After transform succeeds and report finishes", load.Description())
+	assert.True(t, load.NextRun().IsZero())
+
+	cleanup, err := scheduler.GetJob("cleanup")
+	assert.NoError(t, err)
+	assert.Equal(t, "At 03:00, and after load succeeds", cleanup.Description())
+}
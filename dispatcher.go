@@ -0,0 +1,323 @@
+package cronroutine
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+)
+
+// GroupCapacity shares the workers of a Scheduler between groups of jobs.
+type GroupCapacity struct {
+	// Reserved is the number of workers that only run jobs of the group,
+	// so that the group can always run that many at once however busy the
+	// other groups are. Reservations that add up to more than the
+	// scheduler's WorkerCount can not all be kept.
+	Reserved int
+
+	// Weight is the group's share of the workers that are not reserved,
+	// relative to the other groups that have runs waiting. Values less than
+	// 1 are treated as 1.
+	Weight int
+}
+
+// errDispatcherStopped is returned when a run is submitted to a dispatcher
+// that has been stopped.
+var errDispatcherStopped = errors.New("dispatcher is stopped")
+
+// dispatcher runs the scheduled runs of jobs on a fixed number of workers.
+// Each group of jobs has its own queue, ordered by the priority of the jobs
+// and then by when the runs were submitted. When a worker is free it goes to
+// a group that has a reserved worker free, or else to the group using the
+// smallest share of the unreserved workers for its weight, and the group
+// starts the first run in its queue.
+type dispatcher struct {
+	lock    sync.Mutex
+	workers int
+	shared  int
+	groups  map[string]*dispatchGroup
+	running int
+	// sharedBusy is the number of unreserved workers that are running.
+	sharedBusy int
+	nextSeq    uint64
+	stopped    bool
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	metrics *poolMetrics
+	logger  logr.Logger
+}
+
+type dispatchGroup struct {
+	queue    dispatchQueue
+	running  int
+	shared   int
+	reserved int
+	weight   int
+	limit    int
+}
+
+type dispatchTask struct {
+	jobID    string
+	group    *dispatchGroup
+	priority int
+	seq      uint64
+	run      func(ctx context.Context) error
+
+	// finished, if it is not nil, is called with the error run returned
+	// once its worker is free again, or with errDispatcherStopped if the
+	// dispatcher is stopped before the task starts.
+	finished func(err error)
+
+	// shared is whether the task runs on an unreserved worker.
+	shared bool
+}
+
+func newDispatcher(workers int, capacity map[string]GroupCapacity, limits map[string]int, metrics *poolMetrics, logger logr.Logger) *dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &dispatcher{
+		workers: workers,
+		groups:  make(map[string]*dispatchGroup),
+		ctx:     ctx,
+		cancel:  cancel,
+		metrics: metrics,
+		logger:  logger,
+	}
+
+	reserved := 0
+	for name, c := range capacity {
+		g := d.group(name)
+		g.reserved = max(c.Reserved, 0)
+		g.weight = max(c.Weight, 1)
+		reserved += g.reserved
+	}
+	for name, limit := range limits {
+		d.group(name).limit = max(limit, 0)
+	}
+	d.shared = max(workers-reserved, 0)
+
+	return d
+}
+
+// group returns the group called name, creating it if needed. The caller must
+// hold lock, or be the constructor.
+func (d *dispatcher) group(name string) *dispatchGroup {
+	g, ok := d.groups[name]
+	if !ok {
+		g = &dispatchGroup{weight: 1}
+		d.groups[name] = g
+	}
+
+	return g
+}
+
+// submit queues a run of a job in group. It does not wait for the run to
+// start.
+func (d *dispatcher) submit(jobID, group string, priority int, run func(ctx context.Context) error) error {
+	return d.enqueue(group, &dispatchTask{jobID: jobID, priority: priority, run: run})
+}
+
+// run queues a run of a job in group like submit does, and waits for it to
+// finish. The context run is called with is done when either ctx or the
+// dispatcher's context is. If ctx is done before the run starts, run is not
+// called and ctx's cause is returned.
+func (d *dispatcher) run(ctx context.Context, jobID, group string, priority int, run func(ctx context.Context) error) error {
+	// taken is set by whichever of the task and the caller giving up on it
+	// comes first, so that the task does not start after the caller
+	// returned.
+	var taken atomic.Bool
+	result := make(chan error, 1)
+	task := &dispatchTask{
+		jobID:    jobID,
+		priority: priority,
+		run: func(poolCtx context.Context) error {
+			if !taken.CompareAndSwap(false, true) {
+				return nil
+			}
+
+			ctx, cancel := context.WithCancelCause(ctx)
+			defer cancel(nil)
+			stop := context.AfterFunc(poolCtx, func() { cancel(context.Cause(poolCtx)) })
+			defer stop()
+
+			return run(ctx)
+		},
+		finished: func(err error) { result <- err },
+	}
+	if err := d.enqueue(group, task); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		if taken.CompareAndSwap(false, true) {
+			return context.Cause(ctx)
+		}
+		return <-result
+	}
+}
+
+func (d *dispatcher) enqueue(group string, task *dispatchTask) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.stopped {
+		return errDispatcherStopped
+	}
+
+	task.group = d.group(group)
+	task.seq = d.nextSeq
+	heap.Push(&task.group.queue, task)
+	d.nextSeq++
+	d.metrics.queue()
+	d.dispatchLocked()
+
+	return nil
+}
+
+// dispatchLocked starts queued runs while there are workers for them.
+func (d *dispatcher) dispatchLocked() {
+	for d.running < d.workers {
+		g, shared := d.nextGroupLocked()
+		if g == nil {
+			return
+		}
+
+		task := heap.Pop(&g.queue).(*dispatchTask)
+		task.shared = shared
+		g.running++
+		d.running++
+		if shared {
+			g.shared++
+			d.sharedBusy++
+		}
+		d.metrics.start()
+
+		d.wg.Add(1)
+		go d.execute(task)
+	}
+}
+
+// nextGroupLocked returns the group that the next free worker goes to, and
+// whether the worker is an unreserved one, or nil if no group can start a run.
+func (d *dispatcher) nextGroupLocked() (*dispatchGroup, bool) {
+	var best *dispatchGroup
+	bestShared := false
+	for _, g := range d.groups {
+		if len(g.queue) == 0 || (g.limit > 0 && g.running >= g.limit) {
+			continue
+		}
+
+		shared := g.running-g.shared >= g.reserved
+		if shared && d.sharedBusy >= d.shared {
+			continue
+		}
+
+		if best == nil || g.before(best, shared, bestShared) {
+			best, bestShared = g, shared
+		}
+	}
+
+	return best, bestShared
+}
+
+// before reports whether g should get a free worker before other. A group
+// with a reserved worker free goes first, then the one using the smallest
+// share of the unreserved workers for its weight, then the one whose next run
+// has the highest priority and was submitted first.
+func (g *dispatchGroup) before(other *dispatchGroup, shared, otherShared bool) bool {
+	if shared != otherShared {
+		return !shared
+	}
+
+	// Compare g.shared/g.weight with other.shared/other.weight without
+	// dividing.
+	if a, b := g.shared*other.weight, other.shared*g.weight; a != b {
+		return a < b
+	}
+
+	return runsBefore(g.queue[0], other.queue[0])
+}
+
+func (d *dispatcher) execute(task *dispatchTask) {
+	defer d.wg.Done()
+
+	var err error
+	if task.finished != nil {
+		defer func() { task.finished(err) }()
+	}
+	defer func() {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		task.group.running--
+		d.running--
+		if task.shared {
+			task.group.shared--
+			d.sharedBusy--
+		}
+		d.metrics.finish()
+		d.dispatchLocked()
+	}()
+
+	err = task.run(d.ctx)
+}
+
+// stop drops the runs that have not started, cancels the ones that have and
+// waits for them to finish.
+func (d *dispatcher) stop() {
+	d.lock.Lock()
+	d.stopped = true
+	for _, g := range d.groups {
+		for _, task := range g.queue {
+			d.logger.Info("scheduler stopped, dropping run", "job_id", task.jobID)
+			d.metrics.dequeue()
+			if task.finished != nil {
+				task.finished(errDispatcherStopped)
+			}
+		}
+		g.queue = nil
+	}
+	d.lock.Unlock()
+
+	d.cancel()
+	d.wg.Wait()
+}
+
+// dispatchQueue is a heap of the runs waiting in a group, with the run that
+// should start next first.
+type dispatchQueue []*dispatchTask
+
+func (q dispatchQueue) Len() int { return len(q) }
+
+func (q dispatchQueue) Less(i, j int) bool { return runsBefore(q[i], q[j]) }
+
+func (q dispatchQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *dispatchQueue) Push(x any) { *q = append(*q, x.(*dispatchTask)) }
+
+func (q *dispatchQueue) Pop() any {
+	old := *q
+	task := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+
+	return task
+}
+
+// runsBefore reports whether a should start before b: runs of jobs with a
+// higher priority first, and runs of the same priority in the order they were
+// submitted.
+func runsBefore(a, b *dispatchTask) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+
+	return a.seq < b.seq
+}
@@ -0,0 +1,227 @@
+package cronroutine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDispatcher(workers int, capacity map[string]GroupCapacity) *dispatcher {
+	return newDispatcher(workers, capacity, nil, &poolMetrics{metrics: nopMetrics{}, workers: workers}, logr.Discard())
+}
+
+// dispatchRecorder records the runs a dispatcher starts. Its runs wait for
+// release before they finish.
+type dispatchRecorder struct {
+	lock    sync.Mutex
+	started []string
+	running map[string]int
+	release chan struct{}
+}
+
+func newDispatchRecorder() *dispatchRecorder {
+	return &dispatchRecorder{running: map[string]int{}, release: make(chan struct{})}
+}
+
+func (r *dispatchRecorder) run(group, id string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		r.lock.Lock()
+		r.started = append(r.started, id)
+		r.running[group]++
+		r.lock.Unlock()
+
+		<-r.release
+
+		r.lock.Lock()
+		r.running[group]--
+		r.lock.Unlock()
+		return nil
+	}
+}
+
+func (r *dispatchRecorder) startedRuns() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]string(nil), r.started...)
+}
+
+func (r *dispatchRecorder) runningRuns() map[string]int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	running := map[string]int{}
+	for group, n := range r.running {
+		if n > 0 {
+			running[group] = n
+		}
+	}
+	return running
+}
+
+func TestDispatcher_priority(t *testing.T) {
+	t.Parallel()
+	d := newTestDispatcher(1, nil)
+	defer d.stop()
+
+	var lock sync.Mutex
+	var order []string
+	run := func(id string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			lock.Lock()
+			defer lock.Unlock()
+			order = append(order, id)
+			return nil
+		}
+	}
+
+	blocker := newDispatchRecorder()
+	assert.NoError(t, d.submit("blocker", "", 0, blocker.run("", "blocker")))
+	assert.NoError(t, d.submit("low", "", 0, run("low")))
+	assert.NoError(t, d.submit("high", "", 10, run("high")))
+	assert.NoError(t, d.submit("mid", "", 5, run("mid")))
+	assert.NoError(t, d.submit("high-2", "batch", 10, run("high-2")))
+	close(blocker.release)
+
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(order) == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"high", "high-2", "mid", "low"}, order)
+}
+
+func TestDispatcher_reserved(t *testing.T) {
+	t.Parallel()
+	d := newTestDispatcher(3, map[string]GroupCapacity{"critical": {Reserved: 1}})
+	defer d.stop()
+	r := newDispatchRecorder()
+	defer close(r.release)
+
+	for _, id := range []string{"batch-1", "batch-2", "batch-3"} {
+		assert.NoError(t, d.submit(id, "batch", 0, r.run("batch", id)))
+	}
+	assert.Eventually(t, func() bool { return len(r.startedRuns()) == 2 }, time.Second, time.Millisecond)
+
+	// The batch runs can only use the unreserved workers, so a critical run
+	// starts straight away.
+	assert.NoError(t, d.submit("critical", "critical", 0, r.run("critical", "critical")))
+	assert.Eventually(t, func() bool { return len(r.startedRuns()) == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, map[string]int{"batch": 2, "critical": 1}, r.runningRuns())
+}
+
+func TestDispatcher_reservedWorkerFreed(t *testing.T) {
+	t.Parallel()
+	d := newTestDispatcher(2, map[string]GroupCapacity{"a": {Reserved: 1}})
+	defer d.stop()
+	r := newDispatchRecorder()
+	defer close(r.release)
+
+	// a-1 takes the reserved worker and a-2 the unreserved one.
+	first := make(chan struct{})
+	assert.NoError(t, d.submit("a-1", "a", 0, func(ctx context.Context) error {
+		<-first
+		return nil
+	}))
+	assert.NoError(t, d.submit("a-2", "a", 0, r.run("a", "a-2")))
+	assert.Eventually(t, func() bool { return len(r.startedRuns()) == 1 }, time.Second, time.Millisecond)
+
+	// Once a-1 finishes, a-3 gets the reserved worker back even though a-2
+	// is still running, and b-1 waits for the unreserved one.
+	close(first)
+	assert.NoError(t, d.submit("a-3", "a", 0, r.run("a", "a-3")))
+	assert.NoError(t, d.submit("b-1", "b", 0, r.run("b", "b-1")))
+	assert.Eventually(t, func() bool { return len(r.startedRuns()) == 2 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, []string{"a-2", "a-3"}, r.startedRuns())
+}
+
+func TestDispatcher_weights(t *testing.T) {
+	t.Parallel()
+	d := newTestDispatcher(3, map[string]GroupCapacity{"a": {Weight: 2}})
+	defer d.stop()
+
+	blockers := newDispatchRecorder()
+	for _, id := range []string{"blocker-1", "blocker-2", "blocker-3"} {
+		assert.NoError(t, d.submit(id, "", 0, blockers.run("", id)))
+	}
+	assert.Eventually(t, func() bool { return len(blockers.startedRuns()) == 3 }, time.Second, time.Millisecond)
+
+	r := newDispatchRecorder()
+	defer close(r.release)
+	for _, group := range []string{"a", "b"} {
+		for _, id := range []string{"1", "2", "3"} {
+			assert.NoError(t, d.submit(group+"-"+id, group, 0, r.run(group, group+"-"+id)))
+		}
+	}
+	close(blockers.release)
+
+	assert.Eventually(t, func() bool { return len(r.startedRuns()) == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, map[string]int{"a": 2, "b": 1}, r.runningRuns())
+}
+
+func TestDispatcher_stop(t *testing.T) {
+	t.Parallel()
+	d := newTestDispatcher(1, nil)
+
+	var canceled bool
+	assert.NoError(t, d.submit("running", "", 0, func(ctx context.Context) error {
+		<-ctx.Done()
+		canceled = true
+		return ctx.Err()
+	}))
+	ran := false
+	assert.NoError(t, d.submit("queued", "", 0, func(ctx context.Context) error {
+		ran = true
+		return nil
+	}))
+
+	d.stop()
+	assert.True(t, canceled)
+	assert.False(t, ran)
+	assert.Equal(t, errDispatcherStopped, d.submit("late", "", 0, func(ctx context.Context) error { return nil }))
+}
+
+func TestDispatcher_run(t *testing.T) {
+	t.Parallel()
+	d := newTestDispatcher(1, nil)
+	assert.NoError(t, d.submit("blocker", "", 0, func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}))
+
+	// A run whose context is done before a worker is free does not start.
+	ran := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := d.run(ctx, "canceled", "", 0, func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// A run that is queued when the dispatcher stops is dropped.
+	stopped := make(chan error)
+	go func() {
+		stopped <- d.run(context.Background(), "dropped", "", 0, func(ctx context.Context) error {
+			ran <- struct{}{}
+			return nil
+		})
+	}()
+	assert.Eventually(t, func() bool {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		return len(d.groups[""].queue) == 2
+	}, time.Second, time.Millisecond)
+	d.stop()
+
+	assert.Equal(t, errDispatcherStopped, <-stopped)
+	assert.Empty(t, ran)
+}
@@ -1,5 +1,25 @@
 package cronroutine
 
+import "fmt"
+
+// ErrJobNotFound is returned when there is no job with the given ID.
+type ErrJobNotFound struct {
+	ID string
+}
+
+func (e ErrJobNotFound) Error() string {
+	return fmt.Sprintf("job with ID %s does not exist", e.ID)
+}
+
+// ErrGroupNotFound is returned when there are no jobs in the given group.
+type ErrGroupNotFound struct {
+	Group string
+}
+
+func (e ErrGroupNotFound) Error() string {
+	return fmt.Sprintf("no jobs in group %s", e.Group)
+}
+
 type ErrJobRunning struct{}
 
 func (e ErrJobRunning) Error() string {
@@ -17,3 +37,56 @@ type ErrJobTimeout struct{}
 func (e ErrJobTimeout) Error() string {
 	return "job execution context deadline exceeded"
 }
+
+type ErrJobReplaced struct{}
+
+func (e ErrJobReplaced) Error() string {
+	return "job run was canceled because a newer run replaced it"
+}
+
+type ErrQueueFull struct{}
+
+func (e ErrQueueFull) Error() string {
+	return "job could not be queued because too many runs are already waiting"
+}
+
+// ErrRunClaimed is recorded when the Locker reports that another Scheduler has
+// already claimed a scheduled run.
+type ErrRunClaimed struct{}
+
+func (e ErrRunClaimed) Error() string {
+	return "job run was claimed by another scheduler"
+}
+
+// ErrJobPanicked is returned when a job's function panics.
+type ErrJobPanicked struct {
+	// Value is the value the function panicked with.
+	Value any
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e ErrJobPanicked) Error() string {
+	return fmt.Sprintf("job panicked: %v", e.Value)
+}
+
+// ErrCommandFailed is returned by the function of a CommandJob when its
+// process exits with a status other than zero.
+type ErrCommandFailed struct {
+	ExitCode int
+}
+
+func (e ErrCommandFailed) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.ExitCode)
+}
+
+// ErrCommandKilled is returned by the function of a CommandJob when its
+// process is terminated by a signal it did not handle.
+type ErrCommandKilled struct {
+	Signal string
+}
+
+func (e ErrCommandKilled) Error() string {
+	return fmt.Sprintf("command was killed by signal %s", e.Signal)
+}
@@ -0,0 +1,149 @@
+package cronroutine
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	EventJobAdded   EventType = "JobAdded"
+	EventJobRemoved EventType = "JobRemoved"
+	EventJobUpdated EventType = "JobUpdated"
+
+	// EventRunScheduled is sent when a run is queued to start at its
+	// scheduled time.
+	EventRunScheduled EventType = "RunScheduled"
+
+	// EventRunStarted is sent when an attempt of a run starts.
+	EventRunStarted EventType = "RunStarted"
+
+	// EventRunFinished is sent when an attempt of a run finishes for any
+	// reason other than being skipped or timing out.
+	EventRunFinished EventType = "RunFinished"
+
+	// EventRunSkipped is sent when a run does not start, for example
+	// because of its concurrency policy or starting deadline.
+	EventRunSkipped EventType = "RunSkipped"
+
+	// EventRunTimedOut is sent when an attempt of a run exceeds its
+	// timeout.
+	EventRunTimedOut EventType = "RunTimedOut"
+)
+
+// Event describes something the Scheduler did.
+type Event struct {
+	Type  EventType
+	Time  time.Time
+	JobID string
+
+	// Run is the run the event is about. It is nil for job events, and its
+	// Attempt is zero for EventRunScheduled.
+	Run *RunInfo
+
+	// Status and Err are the outcome of the run for EventRunFinished,
+	// EventRunSkipped and EventRunTimedOut.
+	Status RunStatus
+	Err    error
+}
+
+func newRunFinishedEvent(h *History) Event {
+	e := Event{
+		Type:   EventRunFinished,
+		JobID:  h.jobID,
+		Run:    h.runInfo(),
+		Status: h.status,
+		Err:    h.err,
+	}
+
+	switch h.status {
+	case RunSkipped:
+		e.Type = EventRunSkipped
+	case RunTimedOut:
+		e.Type = EventRunTimedOut
+	}
+
+	return e
+}
+
+// Subscription receives the events of a Scheduler. Events are delivered
+// without blocking the Scheduler, so when the subscriber falls behind and the
+// buffer is full, events are dropped and counted.
+type Subscription struct {
+	bus     *eventBus
+	events  chan Event
+	dropped atomic.Uint64
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is closed.
+func (s *Subscription) Events() <-chan Event { return s.events }
+
+// Dropped returns the number of events that were dropped because the buffer
+// was full.
+func (s *Subscription) Dropped() uint64 { return s.dropped.Load() }
+
+// Close stops delivery of events and closes the events channel.
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+type eventBus struct {
+	lock sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *eventBus) subscribe(bufferSize int) *Subscription {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	sub := &Subscription{
+		bus:    b,
+		events: make(chan Event, max(bufferSize, 0)),
+	}
+	b.subs[sub] = struct{}{}
+
+	return sub
+}
+
+func (b *eventBus) unsubscribe(sub *Subscription) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+
+	delete(b.subs, sub)
+	close(sub.events)
+}
+
+func (b *eventBus) publish(e Event) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if len(b.subs) == 0 {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.events <- e:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
@@ -26,11 +26,11 @@ func main() {
 	done := make(chan struct{})
 
 	err = scheduler.AddJob(cronroutine.JobConfig{
-		ID:                  "test",
-		Schedule:            "* * * * *",
-		Timeout:             2 * time.Second,
-		StartingDeadline:    100 * time.Millisecond,
-		AllowConccurentRuns: false,
+		ID:                "test",
+		Schedule:          "* * * * *",
+		Timeout:           2 * time.Second,
+		StartingDeadline:  100 * time.Millisecond,
+		ConcurrencyPolicy: cronroutine.ConcurrencyForbid,
 		Func: func(ctx context.Context) error {
 			fmt.Println("test job finished at", time.Now().Format(time.RFC3339))
 			done <- struct{}{}
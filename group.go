@@ -0,0 +1,128 @@
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// JobFilter selects the jobs ListJobs returns.
+type JobFilter func(j *Job) bool
+
+// HasTag selects the jobs that have tag.
+func HasTag(tag string) JobFilter {
+	return func(j *Job) bool { return slices.Contains(j.jobConfig.Tags, tag) }
+}
+
+// InGroup selects the jobs in group.
+func InGroup(group string) JobFilter {
+	return func(j *Job) bool { return j.jobConfig.Group == group }
+}
+
+// groupJobsLocked returns the jobs in group sorted by ID, or ErrGroupNotFound
+// if there are none. Jobs without a group are not a group of their own. The
+// caller must hold jobsLock.
+func (s *Scheduler) groupJobsLocked(group string) ([]*jobMetadata, error) {
+	if group == "" {
+		return nil, ErrGroupNotFound{Group: group}
+	}
+
+	var jobs []*jobMetadata
+	for _, job := range s.jobs {
+		if job.jobConfig.Group == group {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) == 0 {
+		return nil, ErrGroupNotFound{Group: group}
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID() < jobs[j].ID() })
+	return jobs, nil
+}
+
+func (s *Scheduler) groupJobs(group string) ([]*jobMetadata, error) {
+	s.jobsLock.RLock()
+	defer s.jobsLock.RUnlock()
+
+	return s.groupJobsLocked(group)
+}
+
+// PauseGroup pauses every job in group like PauseJob does.
+func (s *Scheduler) PauseGroup(group string) error {
+	jobs, err := s.groupJobs(group)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		job.setPaused(true)
+	}
+	return nil
+}
+
+// ResumeGroup resumes every job in group like ResumeJob does.
+func (s *Scheduler) ResumeGroup(group string) error {
+	jobs, err := s.groupJobs(group)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, job := range jobs {
+		changed = job.setPaused(false) || changed
+	}
+	if changed {
+		s.wakeQueueLoop()
+	}
+	return nil
+}
+
+// RemoveGroup removes every job in group like RemoveJob does.
+func (s *Scheduler) RemoveGroup(group string) error {
+	s.jobsLock.Lock()
+	defer s.jobsLock.Unlock()
+
+	jobs, err := s.groupJobsLocked(group)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, job := range jobs {
+		if err := s.removeJob(job.ID()); err != nil {
+			errs = append(errs, fmt.Errorf("job %s: %w", job.ID(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// TriggerGroup runs every job in group like TriggerJob does, and waits for all
+// of the runs to finish. The runs share the workers like scheduled runs do, so
+// no more of them run at once than the group's limit allows. The returned
+// error joins the errors of the runs that failed.
+func (s *Scheduler) TriggerGroup(ctx context.Context, group string) error {
+	jobs, err := s.groupJobs(group)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job *jobMetadata) {
+			defer wg.Done()
+			if err := s.runManually(ctx, job.newManualRun()); err != nil {
+				errs[i] = fmt.Errorf("job %s: %w", job.ID(), err)
+			}
+		}(i, job)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,158 @@
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_groups(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	var runs atomic.Int32
+	fn := func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}
+
+	jobs := []JobConfig{
+		{ID: "export", Group: "etl", Tags: []string{"daily", "critical"}, Schedule: "0 0 * * *", Func: fn},
+		{ID: "load", Group: "etl", Tags: []string{"daily"}, Schedule: "0 1 * * *", Func: func(ctx context.Context) error {
+			return errors.New("failed")
+		}},
+		{ID: "report", Tags: []string{"weekly"}, Schedule: "0 0 * * 1", Func: fn},
+	}
+	for _, job := range jobs {
+		assert.NoError(t, scheduler.AddJob(job))
+	}
+
+	assert.Equal(t, []string{"export", "load", "report"}, jobIDs(scheduler))
+	ids := func(jobs []*Job) []string {
+		var ret []string
+		for _, job := range jobs {
+			ret = append(ret, job.ID())
+		}
+		return ret
+	}
+	assert.ElementsMatch(t, []string{"export", "load"}, ids(scheduler.ListJobs(HasTag("daily"))))
+	assert.ElementsMatch(t, []string{"export"}, ids(scheduler.ListJobs(HasTag("daily"), HasTag("critical"))))
+	assert.ElementsMatch(t, []string{"export", "load"}, ids(scheduler.ListJobs(InGroup("etl"))))
+	assert.Empty(t, scheduler.ListJobs(InGroup("etl"), HasTag("weekly")))
+
+	assert.NoError(t, scheduler.PauseGroup("etl"))
+	for _, job := range scheduler.ListJobs() {
+		assert.Equal(t, job.Group() == "etl", job.Paused(), job.ID())
+	}
+	assert.NoError(t, scheduler.ResumeGroup("etl"))
+	assert.Empty(t, scheduler.ListJobs(func(j *Job) bool { return j.Paused() }))
+
+	err := scheduler.TriggerGroup(context.Background(), "etl")
+	assert.EqualError(t, err, "job load: failed")
+	assert.Equal(t, int32(1), runs.Load())
+
+	assert.Equal(t, ErrGroupNotFound{Group: "missing"}, scheduler.PauseGroup("missing"))
+	assert.Equal(t, ErrGroupNotFound{Group: ""}, scheduler.RemoveGroup(""))
+
+	assert.NoError(t, scheduler.RemoveGroup("etl"))
+	assert.Equal(t, []string{"report"}, jobIDs(scheduler))
+}
+
+func TestScheduler_groupLimits(t *testing.T) {
+	t.Parallel()
+	scheduler := StartNewScheduler(&SchedulerConfig{
+		Logger:       logr.Discard(),
+		HistoryLimit: 10,
+		WorkerCount:  4,
+		GroupLimits:  map[string]int{"batch": 1},
+	})
+
+	var lock sync.Mutex
+	running := map[string]int{}
+	peak := map[string]int{}
+	work := func(group string) JobFunc {
+		return func(ctx context.Context) error {
+			lock.Lock()
+			running[group]++
+			peak[group] = max(peak[group], running[group])
+			lock.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			lock.Lock()
+			running[group]--
+			lock.Unlock()
+			return nil
+		}
+	}
+
+	// Dependent runs go through the worker pool, so starting them all at
+	// once shows how many of each group run together.
+	assert.NoError(t, scheduler.AddJob(JobConfig{ID: "start", Schedule: "0 0 1 1 *", Func: func(ctx context.Context) error { return nil }}))
+	for _, id := range []string{"batch-1", "batch-2", "batch-3"} {
+		assert.NoError(t, scheduler.AddJob(JobConfig{ID: id, Group: "batch", DependsOn: []Dependency{{JobID: "start"}}, Func: work("batch")}))
+	}
+	for _, id := range []string{"other-1", "other-2"} {
+		assert.NoError(t, scheduler.AddJob(JobConfig{ID: id, DependsOn: []Dependency{{JobID: "start"}}, Func: work("")}))
+	}
+
+	assert.NoError(t, scheduler.TriggerJob(context.Background(), "start"))
+	assert.Eventually(t, func() bool {
+		for _, job := range scheduler.ListJobs() {
+			if len(job.History()) == 0 {
+				return false
+			}
+		}
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 1, peak["batch"])
+	assert.Equal(t, 2, peak[""])
+}
+
+func TestScheduler_TriggerGroupRespectsLimits(t *testing.T) {
+	t.Parallel()
+	scheduler := StartNewScheduler(&SchedulerConfig{
+		Logger:       logr.Discard(),
+		HistoryLimit: 10,
+		WorkerCount:  4,
+		GroupLimits:  map[string]int{"batch": 2},
+	})
+
+	var lock sync.Mutex
+	running, peak := 0, 0
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, scheduler.AddJob(JobConfig{
+			ID:       fmt.Sprintf("batch-%d", i),
+			Group:    "batch",
+			Schedule: "0 0 1 1 *",
+			Func: func(ctx context.Context) error {
+				lock.Lock()
+				running++
+				peak = max(peak, running)
+				lock.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				lock.Lock()
+				running--
+				lock.Unlock()
+				return nil
+			},
+		}))
+	}
+
+	assert.NoError(t, scheduler.TriggerGroup(context.Background(), "batch"))
+	assert.Equal(t, 2, peak)
+	for _, job := range scheduler.ListJobs(InGroup("batch")) {
+		assert.Len(t, job.History(), 1, job.ID())
+	}
+}
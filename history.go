@@ -1,13 +1,101 @@
 package cronroutine
 
-import "time"
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+// RunStatus describes how a run of a job ended.
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "Succeeded"
+	RunFailed    RunStatus = "Failed"
+	RunTimedOut  RunStatus = "TimedOut"
+	RunSkipped   RunStatus = "Skipped"
+	RunCanceled  RunStatus = "Canceled"
+	RunPanicked  RunStatus = "Panicked"
+)
+
+func statusOf(err error) RunStatus {
+	switch {
+	case err == nil:
+		return RunSucceeded
+	case errors.Is(err, ErrJobRunning{}),
+		errors.Is(err, ErrPastStartingDeadline{}),
+		errors.Is(err, ErrQueueFull{}),
+		errors.Is(err, ErrRunClaimed{}):
+		return RunSkipped
+	case errors.Is(err, ErrJobTimeout{}), errors.Is(err, context.DeadlineExceeded):
+		return RunTimedOut
+	case errors.As(err, &ErrJobPanicked{}):
+		return RunPanicked
+	case errors.Is(err, ErrJobReplaced{}), errors.Is(err, context.Canceled):
+		return RunCanceled
+	default:
+		return RunFailed
+	}
+}
 
 type History struct {
-	jobID string
-	ranAt time.Time
-	err   error
+	jobID       string
+	runID       string
+	trigger     Trigger
+	attempt     int
+	status      RunStatus
+	err         error
+	result      string
+	scheduledAt time.Time
+	startedAt   time.Time
+	finishedAt  time.Time
+
+	// upstreamRunIDs are the runs that started this one when its trigger
+	// is TriggerDependency.
+	upstreamRunIDs []string
+}
+
+func (h *History) JobID() string           { return h.jobID }
+func (h *History) RunID() string           { return h.runID }
+func (h *History) Trigger() Trigger        { return h.trigger }
+func (h *History) Attempt() int            { return h.attempt }
+func (h *History) Status() RunStatus       { return h.status }
+func (h *History) Error() error            { return h.err }
+func (h *History) Result() string          { return h.result }
+func (h *History) ScheduledAt() time.Time  { return h.scheduledAt }
+func (h *History) StartedAt() time.Time    { return h.startedAt }
+func (h *History) FinishedAt() time.Time   { return h.finishedAt }
+func (h *History) Duration() time.Duration { return h.finishedAt.Sub(h.startedAt) }
+
+// UpstreamRunIDs returns the IDs of the runs of upstream jobs that started
+// this run, if its trigger is TriggerDependency.
+func (h *History) UpstreamRunIDs() []string { return slices.Clone(h.upstreamRunIDs) }
+
+// Lag is how long after its scheduled time the run started.
+func (h *History) Lag() time.Duration { return h.startedAt.Sub(h.scheduledAt) }
+
+func (h *History) runInfo() *RunInfo {
+	return &RunInfo{
+		JobID:          h.jobID,
+		RunID:          h.runID,
+		Trigger:        h.trigger,
+		ScheduledTime:  h.scheduledAt,
+		Attempt:        h.attempt,
+		UpstreamRunIDs: slices.Clone(h.upstreamRunIDs),
+	}
 }
 
-func (h *History) JobID() string    { return h.jobID }
-func (h *History) RanAt() time.Time { return h.ranAt }
-func (h *History) Error() error     { return h.err }
+// RanAt returns the time the run was scheduled for.
+//
+// Deprecated: use ScheduledAt.
+func (h *History) RanAt() time.Time { return h.scheduledAt }
+
+// sortHistory sorts runs by the time they were scheduled for, keeping attempts
+// of the same run in order.
+func sortHistory(runs []*History) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].scheduledAt.Before(runs[j].scheduledAt)
+	})
+}
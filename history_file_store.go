@@ -0,0 +1,239 @@
+package cronroutine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// FileHistoryStoreConfig configures a FileHistoryStore.
+type FileHistoryStoreConfig struct {
+	// Path is the file the history is stored in. It is created if it does
+	// not exist.
+	Path string
+
+	// MaxRunsPerJob is the number of most recent runs kept for each job.
+	// Zero means no limit.
+	MaxRunsPerJob int
+
+	// MaxAge is how long runs are kept after they finish. Zero means no
+	// limit.
+	MaxAge time.Duration
+
+	// Logger reports problems with the file that the store recovers from.
+	// If it is the zero value, nothing is logged.
+	Logger logr.Logger
+}
+
+// FileHistoryStore is a HistoryStore that appends every run to a file as a line
+// of JSON, so that history survives restarts. The retained runs are also kept
+// in memory to answer queries, and the file is compacted once most of its lines
+// are past retention.
+type FileHistoryStore struct {
+	lock   sync.Mutex
+	path   string
+	file   *os.File
+	lines  int
+	index  *historyIndex
+	logger logr.Logger
+}
+
+type historyRecord struct {
+	JobID       string    `json:"job_id"`
+	RunID       string    `json:"run_id"`
+	Trigger     Trigger   `json:"trigger"`
+	Attempt     int       `json:"attempt"`
+	Status      RunStatus `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Result      string    `json:"result,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+
+	UpstreamRunIDs []string `json:"upstream_run_ids,omitempty"`
+}
+
+func newHistoryRecord(h *History) *historyRecord {
+	r := &historyRecord{
+		JobID:       h.jobID,
+		RunID:       h.runID,
+		Trigger:     h.trigger,
+		Attempt:     h.attempt,
+		Status:      h.status,
+		Result:      h.result,
+		ScheduledAt: h.scheduledAt,
+		StartedAt:   h.startedAt,
+		FinishedAt:  h.finishedAt,
+
+		UpstreamRunIDs: h.upstreamRunIDs,
+	}
+	if h.err != nil {
+		r.Error = h.err.Error()
+	}
+
+	return r
+}
+
+func (r *historyRecord) history() *History {
+	h := &History{
+		jobID:       r.JobID,
+		runID:       r.RunID,
+		trigger:     r.Trigger,
+		attempt:     r.Attempt,
+		status:      r.Status,
+		result:      r.Result,
+		scheduledAt: r.ScheduledAt,
+		startedAt:   r.StartedAt,
+		finishedAt:  r.FinishedAt,
+
+		upstreamRunIDs: r.UpstreamRunIDs,
+	}
+	if r.Error != "" {
+		h.err = errors.New(r.Error)
+	}
+
+	return h
+}
+
+// NewFileHistoryStore opens the history file at cfg.Path, loading the runs
+// that are still within retention. A last line that can not be parsed, because
+// the process stopped while it was being written, is dropped from the file.
+// Lines before it that can not be parsed are an error.
+func NewFileHistoryStore(cfg *FileHistoryStoreConfig) (*FileHistoryStore, error) {
+	maxRunsPerJob := cfg.MaxRunsPerJob
+	if maxRunsPerJob <= 0 {
+		maxRunsPerJob = -1
+	}
+
+	s := &FileHistoryStore{
+		path:   cfg.Path,
+		index:  newHistoryIndex(maxRunsPerJob, cfg.MaxAge),
+		logger: cfg.Logger,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileHistoryStore) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	// invalid is the error of the last line read if it could not be
+	// parsed. It is only returned if another line follows.
+	var invalid error
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if invalid != nil {
+			return invalid
+		}
+
+		record := &historyRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			invalid = fmt.Errorf("failed to parse history file line %d: %w", line, err)
+			continue
+		}
+		s.index.add(record.history())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if invalid != nil {
+		s.logger.Error(invalid, "dropping partly written last line of history file", "path", s.path)
+	}
+
+	return nil
+}
+
+// compact rewrites the history file with only the retained runs.
+func (s *FileHistoryStore) compact() error {
+	runs := s.index.query(HistoryQuery{})
+
+	err := writeFileAtomic(s.path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, h := range runs {
+			if err := enc.Encode(newHistoryRecord(h)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compact history file: %w", err)
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	s.lines = len(runs)
+
+	return nil
+}
+
+func (s *FileHistoryStore) Add(h *History) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, err := json.Marshal(newHistoryRecord(h))
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	if _, err := s.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	s.lines++
+	s.index.add(h)
+
+	// Compact once the file holds more expired runs than retained ones.
+	if retained := s.index.len(); s.lines-retained > max(retained, 100) {
+		return s.compact()
+	}
+
+	return nil
+}
+
+func (s *FileHistoryStore) Query(q HistoryQuery) ([]*History, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.index.query(q), nil
+}
+
+// Close closes the history file.
+func (s *FileHistoryStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.file.Close()
+}
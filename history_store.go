@@ -0,0 +1,157 @@
+package cronroutine
+
+import (
+	"sync"
+	"time"
+)
+
+// HistoryStore stores the History of job runs. Implementations must be safe
+// for concurrent use.
+type HistoryStore interface {
+	// Add records the result of a run.
+	Add(h *History) error
+
+	// Query returns the runs matching q, oldest first.
+	Query(q HistoryQuery) ([]*History, error)
+}
+
+// HistoryQuery selects runs from a HistoryStore. Zero values match everything.
+type HistoryQuery struct {
+	// JobID only matches runs of the job with this ID.
+	JobID string
+
+	// Since only matches runs scheduled at or after this time.
+	Since time.Time
+
+	// Until only matches runs scheduled before this time.
+	Until time.Time
+
+	// Limit only returns the most recent Limit matching runs.
+	Limit int
+}
+
+func (q HistoryQuery) matches(h *History) bool {
+	if q.JobID != "" && q.JobID != h.jobID {
+		return false
+	}
+
+	if !q.Since.IsZero() && h.scheduledAt.Before(q.Since) {
+		return false
+	}
+
+	if !q.Until.IsZero() && !h.scheduledAt.Before(q.Until) {
+		return false
+	}
+
+	return true
+}
+
+// historyIndex keeps runs in memory grouped by job and applies retention.
+type historyIndex struct {
+	runs          map[string][]*History
+	maxRunsPerJob int
+	maxAge        time.Duration
+}
+
+func newHistoryIndex(maxRunsPerJob int, maxAge time.Duration) *historyIndex {
+	return &historyIndex{
+		runs:          make(map[string][]*History),
+		maxRunsPerJob: maxRunsPerJob,
+		maxAge:        maxAge,
+	}
+}
+
+func (i *historyIndex) add(h *History) {
+	i.runs[h.jobID] = append(i.runs[h.jobID], h)
+	i.retain(h.jobID)
+}
+
+// retain drops the runs of jobID that are past the retention limits.
+func (i *historyIndex) retain(jobID string) {
+	runs := i.runs[jobID]
+	if i.maxAge > 0 {
+		cutoff := time.Now().UTC().Add(-i.maxAge)
+		for len(runs) > 0 && runs[0].finishedAt.Before(cutoff) {
+			runs = runs[1:]
+		}
+	}
+
+	if i.maxRunsPerJob >= 0 && len(runs) > i.maxRunsPerJob {
+		runs = runs[len(runs)-i.maxRunsPerJob:]
+	}
+
+	if len(runs) == 0 {
+		delete(i.runs, jobID)
+		return
+	}
+
+	i.runs[jobID] = runs
+}
+
+func (i *historyIndex) len() int {
+	n := 0
+	for _, runs := range i.runs {
+		n += len(runs)
+	}
+
+	return n
+}
+
+func (i *historyIndex) query(q HistoryQuery) []*History {
+	var candidates [][]*History
+	if q.JobID != "" {
+		i.retain(q.JobID)
+		candidates = append(candidates, i.runs[q.JobID])
+	} else {
+		for jobID := range i.runs {
+			i.retain(jobID)
+			candidates = append(candidates, i.runs[jobID])
+		}
+	}
+
+	ret := []*History{}
+	for _, runs := range candidates {
+		for _, h := range runs {
+			if q.matches(h) {
+				ret = append(ret, h)
+			}
+		}
+	}
+
+	sortHistory(ret)
+	if q.Limit > 0 && len(ret) > q.Limit {
+		ret = ret[len(ret)-q.Limit:]
+	}
+
+	return ret
+}
+
+// MemoryHistoryStore keeps the most recent runs of every job in memory. It is
+// the default HistoryStore.
+type MemoryHistoryStore struct {
+	lock  sync.Mutex
+	index *historyIndex
+}
+
+// NewMemoryHistoryStore returns a HistoryStore that keeps at most limit runs
+// per job.
+func NewMemoryHistoryStore(limit int) *MemoryHistoryStore {
+	return &MemoryHistoryStore{
+		index: newHistoryIndex(max(limit, 0), 0),
+	}
+}
+
+func (s *MemoryHistoryStore) Add(h *History) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.index.add(h)
+	return nil
+}
+
+func (s *MemoryHistoryStore) Query(q HistoryQuery) ([]*History, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.index.query(q), nil
+}
@@ -0,0 +1,148 @@
+package cronroutine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHistory(jobID string, scheduledAt time.Time, err error) *History {
+	return &History{
+		jobID:       jobID,
+		runID:       newRunID(),
+		trigger:     TriggerSchedule,
+		attempt:     1,
+		status:      statusOf(err),
+		err:         err,
+		scheduledAt: scheduledAt,
+		startedAt:   scheduledAt,
+		finishedAt:  time.Now().UTC(),
+	}
+}
+
+func TestMemoryHistoryStore(t *testing.T) {
+	store := NewMemoryHistoryStore(2)
+	start := time.Date(2021, time.January, 3, 14, 0, second, nanosecond, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, store.Add(newTestHistory("a", start.Add(time.Duration(i)*time.Minute), nil)))
+	}
+	assert.NoError(t, store.Add(newTestHistory("b", start, nil)))
+
+	history, err := store.Query(HistoryQuery{JobID: "a"})
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, start.Add(time.Minute), history[0].ScheduledAt())
+	assert.Equal(t, start.Add(2*time.Minute), history[1].ScheduledAt())
+
+	history, err = store.Query(HistoryQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, "b", history[0].JobID())
+}
+
+func TestFileHistoryStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	start := time.Date(2021, time.January, 3, 14, 0, second, nanosecond, time.UTC)
+
+	store, err := NewFileHistoryStore(&FileHistoryStoreConfig{Path: path, MaxRunsPerJob: 3})
+	assert.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		var runErr error
+		if i%2 == 1 {
+			runErr = errors.New("failed")
+		}
+		assert.NoError(t, store.Add(newTestHistory("a", start.Add(time.Duration(i)*time.Minute), runErr)))
+	}
+	assert.NoError(t, store.Add(newTestHistory("b", start, nil)))
+	assert.NoError(t, store.Close())
+
+	store, err = NewFileHistoryStore(&FileHistoryStoreConfig{Path: path, MaxRunsPerJob: 3})
+	assert.NoError(t, err)
+	defer store.Close()
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(contents), "\n"), "file should be compacted on open")
+
+	history, err := store.Query(HistoryQuery{JobID: "a"})
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	assert.Equal(t, start.Add(2*time.Minute), history[0].ScheduledAt())
+	assert.Equal(t, RunFailed, history[1].Status())
+	assert.EqualError(t, history[1].Error(), "failed")
+	assert.Equal(t, RunSucceeded, history[2].Status())
+	assert.NoError(t, history[2].Error())
+
+	history, err = store.Query(HistoryQuery{
+		JobID: "a",
+		Since: start.Add(3 * time.Minute),
+		Until: start.Add(4 * time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, start.Add(3*time.Minute), history[0].ScheduledAt())
+
+	history, err = store.Query(HistoryQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, start.Add(3*time.Minute), history[0].ScheduledAt())
+}
+
+func TestFileHistoryStore_partialLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	start := time.Date(2021, time.January, 3, 14, 0, second, nanosecond, time.UTC)
+
+	store, err := NewFileHistoryStore(&FileHistoryStoreConfig{Path: path})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Add(newTestHistory("a", start, nil)))
+	assert.NoError(t, store.Close())
+
+	// The process stopped while it was adding a second run.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"job_id":"a","run_id":`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	store, err = NewFileHistoryStore(&FileHistoryStoreConfig{Path: path})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Add(newTestHistory("a", start.Add(time.Minute), nil)))
+	assert.NoError(t, store.Close())
+
+	store, err = NewFileHistoryStore(&FileHistoryStoreConfig{Path: path})
+	assert.NoError(t, err)
+	defer store.Close()
+	history, err := store.Query(HistoryQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	// Lines that can not be parsed before the last one are still an error.
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, append([]byte("{\n"), contents...), 0o644))
+	_, err = NewFileHistoryStore(&FileHistoryStoreConfig{Path: path})
+	assert.ErrorContains(t, err, "failed to parse history file line 1")
+}
+
+func TestFileHistoryStore_maxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := NewFileHistoryStore(&FileHistoryStoreConfig{Path: path, MaxAge: time.Hour})
+	assert.NoError(t, err)
+	defer store.Close()
+
+	old := newTestHistory("a", time.Now().UTC().Add(-2*time.Hour), nil)
+	old.finishedAt = old.scheduledAt
+	assert.NoError(t, store.Add(old))
+	assert.NoError(t, store.Add(newTestHistory("a", time.Now().UTC(), nil)))
+
+	history, err := store.Query(HistoryQuery{JobID: "a"})
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+}
@@ -1,7 +1,7 @@
 package cronroutine
 
 import (
-	"context"
+	"slices"
 	"time"
 )
 
@@ -12,35 +12,144 @@ type JobConfig struct {
 	// Schedule is the cron schedule that determines when the job will run.
 	Schedule string
 
+	// Location is the time zone the schedule is evaluated in. If it is nil,
+	// the schedule is evaluated in UTC.
+	Location *time.Location
+
 	// Timeout is the amount of time each instance of the job is allowed to
-	// run before it is killed.
+	// run before it is killed. Zero means no timeout.
 	Timeout time.Duration
 
 	// StartingDeadline is the maximum time the job can be delayed. If the
-	// job is delayed more than this, it will be skipped.
+	// job is delayed more than this, it will be skipped. Zero means the job
+	// is never skipped for being late.
 	StartingDeadline time.Duration
 
 	// AllowConccurentRuns determines whether the next job will start
 	// if it is currently running.
+	//
+	// Deprecated: use ConcurrencyPolicy. AllowConccurentRuns is only
+	// consulted when ConcurrencyPolicy is empty, in which case true means
+	// ConcurrencyAllow and false means ConcurrencyForbid.
 	AllowConccurentRuns bool
 
+	// ConcurrencyPolicy determines what happens when the job is due to run
+	// while a previous run is still active.
+	ConcurrencyPolicy ConcurrencyPolicy
+
+	// QueueDepth is the maximum number of runs that may wait for an active
+	// run to finish when ConcurrencyPolicy is ConcurrencyQueue. Values less
+	// than 1 are treated as 1.
+	QueueDepth int
+
+	// MisfirePolicy determines what happens to fire times that were missed,
+	// for example because the process was down or the job was paused.
+	MisfirePolicy MisfirePolicy
+
+	// MisfireLimit is the maximum number of missed runs that are started when
+	// MisfirePolicy is MisfireRunAll. Zero means no limit.
+	MisfireLimit int
+
+	// Retry determines whether failed runs are retried. If it is nil, failed
+	// runs are not retried.
+	Retry *RetryPolicy
+
+	// Middleware wraps Func every time the job runs. It runs inside the
+	// scheduler's middleware.
+	Middleware []Middleware
+
+	// Hooks are called as the job's runs start and finish, after the
+	// scheduler's hooks.
+	Hooks Hooks
+
+	// Group is the group the job belongs to, if any. Groups can be paused,
+	// resumed, removed and triggered together, and the scheduler can limit
+	// how many runs of the jobs in a group run at once and reserve workers
+	// for them.
+	Group string
+
+	// Tags label the job so that ListJobs can select it.
+	Tags []string
+
+	// Priority orders the runs of the job's group that are waiting for a
+	// worker. Runs of jobs with a higher priority start first, and runs of
+	// the same priority in the order they were scheduled.
+	Priority int
+
+	// DependsOn makes the job run after runs of other jobs finish, as well
+	// as on its Schedule, which may be empty if the job has dependencies.
+	// With several dependencies the job runs once all of them have been
+	// satisfied since it last ran because of them.
+	DependsOn []Dependency
+
 	// This function will be run when the job is executed.
-	Func func(context.Context) error
+	Func JobFunc
+
+	// Fingerprint identifies what Func, Middleware, Hooks and the Retryable
+	// function of Retry do, for Reconcile, which can not compare functions.
+	// If it is empty, Reconcile updates the job every time. Otherwise it
+	// only updates the job when Fingerprint or another field changed, so
+	// Fingerprint must change whenever the functions do, including the
+	// values that closures capture.
+	Fingerprint string
+}
+
+func (c *JobConfig) concurrencyPolicy() ConcurrencyPolicy {
+	if c.ConcurrencyPolicy != "" {
+		return c.ConcurrencyPolicy
+	}
+
+	if c.AllowConccurentRuns {
+		return ConcurrencyAllow
+	}
+
+	return ConcurrencyForbid
+}
+
+func (c *JobConfig) queueDepth() int {
+	return max(c.QueueDepth, 1)
 }
 
 type Job struct {
 	jobConfig *JobConfig
 	history   []*History
 	cron      *Cron
+	state     JobState
 }
 
-func (j *Job) ID() string                          { return j.jobConfig.ID }
-func (j *Job) Schedule() string                    { return j.jobConfig.Schedule }
-func (j *Job) Timeout() time.Duration              { return j.jobConfig.Timeout }
-func (j *Job) StartingDeadline() time.Duration     { return j.jobConfig.StartingDeadline }
-func (j *Job) AllowConccurentRuns() bool           { return j.jobConfig.AllowConccurentRuns }
-func (j *Job) NextRun() time.Time                  { return j.cron.Next() }
-func (j *Job) NextFor(t time.Duration) []time.Time { return j.cron.NextFor(t) }
+func (j *Job) ID() string                           { return j.jobConfig.ID }
+func (j *Job) Schedule() string                     { return j.jobConfig.Schedule }
+func (j *Job) Location() *time.Location             { return j.cron.location() }
+func (j *Job) Timeout() time.Duration               { return j.jobConfig.Timeout }
+func (j *Job) StartingDeadline() time.Duration      { return j.jobConfig.StartingDeadline }
+func (j *Job) AllowConccurentRuns() bool            { return j.ConcurrencyPolicy() == ConcurrencyAllow }
+func (j *Job) ConcurrencyPolicy() ConcurrencyPolicy { return j.jobConfig.concurrencyPolicy() }
+func (j *Job) QueueDepth() int                      { return j.jobConfig.QueueDepth }
+func (j *Job) MisfirePolicy() MisfirePolicy         { return j.jobConfig.MisfirePolicy }
+func (j *Job) MisfireLimit() int                    { return j.jobConfig.MisfireLimit }
+func (j *Job) Retry() *RetryPolicy                  { return j.jobConfig.Retry }
+func (j *Job) DependsOn() []Dependency              { return slices.Clone(j.jobConfig.DependsOn) }
+func (j *Job) Group() string                        { return j.jobConfig.Group }
+func (j *Job) Tags() []string                       { return slices.Clone(j.jobConfig.Tags) }
+func (j *Job) Priority() int                        { return j.jobConfig.Priority }
+func (j *Job) Paused() bool                         { return j.state.Paused }
+func (j *Job) State() JobState                      { return j.state }
+func (j *Job) NextRun() time.Time                   { return j.cron.Next() }
+func (j *Job) NextFor(t time.Duration) []time.Time  { return j.cron.NextFor(t) }
+func (j *Job) NextN(n int) []time.Time              { return j.cron.NextN(n) }
+
+// Description describes the job's schedule and dependencies in English.
+func (j *Job) Description() string {
+	deps := describeDependencies(j.jobConfig.DependsOn)
+	switch {
+	case deps == "":
+		return j.cron.Describe()
+	case j.jobConfig.Schedule == "":
+		return capitalize(deps)
+	default:
+		return j.cron.Describe() + ", and " + deps
+	}
+}
 
 func (j *Job) History() []*History {
 	ret := make([]*History, len(j.history))
@@ -2,6 +2,10 @@ package cronroutine
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
 	"sync"
 	"time"
 
@@ -10,10 +14,68 @@ import (
 
 type jobMetadata struct {
 	jobConfig    *JobConfig
-	history      []*History
+	fn           JobFunc
+	hooks        []Hooks
+	logger       logr.Logger
+	historyStore HistoryStore
 	historyLimit int
 	cron         *Cron
-	running      sync.Mutex
+
+	// recoverPanics determines whether a panic in the job's function is
+	// converted into ErrJobPanicked or allowed to crash the process.
+	recoverPanics bool
+
+	stateStore StateStore
+	locker     Locker
+	events     *eventBus
+	metrics    Metrics
+	tracer     Tracer
+
+	// upstreamDone is called when a run has finished all of its attempts,
+	// to start the jobs that depend on this one.
+	upstreamDone func(r *scheduledJob, err error)
+
+	// current reports whether the job still has this metadata, rather than
+	// having been removed or updated.
+	current func(j *jobMetadata) bool
+
+	// lastScheduled is the last fire time handed to the worker loop. It is
+	// only accessed by the queue loop.
+	lastScheduled time.Time
+
+	*jobRuntime
+}
+
+// jobRuntime is the part of a job's metadata that is carried over when the
+// job is updated: its state and the runs that are in progress.
+type jobRuntime struct {
+	stateLock sync.Mutex
+	state     JobState
+
+	// missed holds the fire times that the worker loop dropped because the
+	// job was paused, so that they are handled by the misfire policy once
+	// the job is resumed. It is guarded by stateLock.
+	missed []time.Time
+
+	runLock   sync.Mutex
+	active    map[uint64]context.CancelCauseFunc
+	nextRunID uint64
+	waiting   []chan struct{}
+	reserved  int
+
+	// satisfied holds the ID of the latest upstream run that satisfied
+	// each of the job's dependencies since the job last ran because of
+	// them.
+	depsLock  sync.Mutex
+	satisfied map[string]string
+}
+
+func newJobRuntime(state JobState) *jobRuntime {
+	return &jobRuntime{
+		state:     state,
+		active:    make(map[uint64]context.CancelCauseFunc),
+		satisfied: make(map[string]string),
+	}
 }
 
 func (j *jobMetadata) ID() string {
@@ -21,24 +83,27 @@ func (j *jobMetadata) ID() string {
 }
 
 func (j *jobMetadata) History() []*History {
-	ret := make([]*History, len(j.history))
-	if copy(ret, j.history) != len(j.history) {
-		panic("unexpected history copy length")
+	history, err := j.historyStore.Query(HistoryQuery{JobID: j.ID(), Limit: j.historyLimit})
+	if err != nil {
+		j.logger.Error(err, "failed to query job history")
+		return []*History{}
 	}
 
-	return ret
+	return history
 }
 
-func (j *jobMetadata) addResult(ranAt time.Time, err error) {
-	j.history = append(j.history, &History{
-		jobID: j.ID(),
-		ranAt: ranAt,
-		err:   err,
-	})
+func (j *jobMetadata) addResult(h *History, err error) {
+	h.status = statusOf(err)
+	h.err = err
+	h.finishedAt = time.Now().UTC()
 
-	if len(j.history) > j.historyLimit {
-		j.history = j.history[1:]
+	if err := j.historyStore.Add(h); err != nil {
+		j.logger.Error(err, "failed to record job history", "run_id", h.runID)
 	}
+
+	j.events.publish(newRunFinishedEvent(h))
+	j.metrics.RunFinished(h)
+	j.onFinish(h)
 }
 
 func (j *jobMetadata) Job() *Job {
@@ -46,9 +111,21 @@ func (j *jobMetadata) Job() *Job {
 		jobConfig: &JobConfig{
 			ID:                  j.jobConfig.ID,
 			Schedule:            j.jobConfig.Schedule,
+			Location:            j.jobConfig.Location,
 			Timeout:             j.jobConfig.Timeout,
 			StartingDeadline:    j.jobConfig.StartingDeadline,
 			AllowConccurentRuns: j.jobConfig.AllowConccurentRuns,
+			ConcurrencyPolicy:   j.jobConfig.ConcurrencyPolicy,
+			QueueDepth:          j.jobConfig.QueueDepth,
+			MisfirePolicy:       j.jobConfig.MisfirePolicy,
+			MisfireLimit:        j.jobConfig.MisfireLimit,
+			Retry:               j.jobConfig.Retry,
+			Middleware:          j.jobConfig.Middleware,
+			Hooks:               j.jobConfig.Hooks,
+			Group:               j.jobConfig.Group,
+			Tags:                j.jobConfig.Tags,
+			Priority:            j.jobConfig.Priority,
+			DependsOn:           j.jobConfig.DependsOn,
 			Func:                j.jobConfig.Func,
 		},
 		history: j.History(),
@@ -58,73 +135,311 @@ func (j *jobMetadata) Job() *Job {
 			DayOfMonth: j.cron.DayOfMonth,
 			Month:      j.cron.Month,
 			DayOfWeek:  j.cron.DayOfWeek,
+			Location:   j.cron.Location,
 		},
+		state: j.State(),
 	}
 }
 
-func (j *jobMetadata) shouldRun(startTime time.Time) error {
-	deadline := startTime.Add(j.jobConfig.StartingDeadline)
-	if j.jobConfig.AllowConccurentRuns {
-		if time.Now().UTC().After(deadline) {
-			return ErrPastStartingDeadline{}
+func (j *jobMetadata) State() JobState {
+	j.stateLock.Lock()
+	defer j.stateLock.Unlock()
+
+	return j.state
+}
+
+// updateState applies update to the job's state and saves it to the
+// StateStore, if there is one.
+func (j *jobMetadata) updateState(update func(state *JobState)) {
+	j.stateLock.Lock()
+	defer j.stateLock.Unlock()
+
+	update(&j.state)
+	if j.stateStore == nil {
+		return
+	}
+
+	if err := j.stateStore.Save(j.ID(), &j.state); err != nil {
+		j.logger.Error(err, "failed to save job state")
+	}
+}
+
+func (j *jobMetadata) isPaused() bool {
+	return j.State().Paused
+}
+
+// setPaused pauses or resumes the job and reports whether that changed
+// anything.
+func (j *jobMetadata) setPaused(paused bool) bool {
+	changed := false
+	j.updateState(func(state *JobState) {
+		changed = state.Paused != paused
+		state.Paused = paused
+	})
+
+	return changed
+}
+
+// dropIfPaused reports whether the job is paused, in which case r must not run.
+// The fire time of a scheduled run is remembered as missed.
+func (j *jobMetadata) dropIfPaused(r *scheduledJob) bool {
+	j.stateLock.Lock()
+	defer j.stateLock.Unlock()
+
+	if !j.state.Paused {
+		return false
+	}
+
+	if r.trigger.scheduled() {
+		j.missed = append(j.missed, r.scheduledTime)
+	}
+	return true
+}
+
+// takeMissed returns the fire times dropped by dropIfPaused, oldest first, and
+// forgets them.
+func (j *jobMetadata) takeMissed() []time.Time {
+	j.stateLock.Lock()
+	defer j.stateLock.Unlock()
+
+	missed := j.missed
+	j.missed = nil
+	slices.SortFunc(missed, func(a, b time.Time) int { return a.Compare(b) })
+
+	return missed
+}
+
+// lastScheduledRun returns the scheduled time of the job's most recent run
+// that was for a fire time of its schedule, or the zero time if it has none.
+func (j *jobMetadata) lastScheduledRun() time.Time {
+	history := j.History()
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Trigger().scheduled() {
+			return history[i].ScheduledAt()
 		}
-		return nil
 	}
 
-	lockAquired := make(chan struct{})
-	go func() {
-		j.running.Lock()
-		lockAquired <- struct{}{}
-	}()
+	return time.Time{}
+}
 
-	startingDeadlineExceeded := make(chan struct{})
-	go func() {
-		time.Sleep(time.Until(deadline))
-		startingDeadlineExceeded <- struct{}{}
-	}()
+// recordOutcome updates the job's state once a run has finished all of its
+// attempts.
+func (j *jobMetadata) recordOutcome(err error) {
+	switch statusOf(err) {
+	case RunSucceeded:
+		j.updateState(func(state *JobState) {
+			state.LastSuccess = time.Now().UTC()
+			state.ConsecutiveFailures = 0
+		})
+	case RunFailed, RunTimedOut, RunPanicked:
+		j.updateState(func(state *JobState) {
+			state.LastFailure = time.Now().UTC()
+			state.ConsecutiveFailures++
+		})
+	}
+}
 
-	select {
-	case <-lockAquired:
-		return nil
-	case <-startingDeadlineExceeded:
-		return ErrJobRunning{}
+// nextRuns returns the runs between now and horizon that have not been
+// scheduled yet. Fire times that were missed since the job was last scheduled,
+// or that were dropped because the job was paused, are handled according to
+// the job's misfire policy, and the ones that should be caught up are started
+// right away.
+func (j *jobMetadata) nextRuns(logger logr.Logger, now time.Time, horizon time.Time) []*scheduledJob {
+	if j.lastScheduled.IsZero() {
+		j.lastScheduled = now
+		if state := j.State(); !state.LastScheduled.IsZero() {
+			j.lastScheduled = state.LastScheduled
+		} else if j.stateStore == nil {
+			// Without a StateStore the history is the only record of
+			// the runs from before a restart. With one, a job without
+			// saved state has never run.
+			if t := j.lastScheduledRun(); !t.IsZero() {
+				j.lastScheduled = t
+			}
+		}
 	}
+
+	// Fire times that passed less than misfireTolerance ago, because the
+	// queue loop woke up a little late, are run as scheduled rather than
+	// missed.
+	late := now.Add(-misfireTolerance)
+	missed := j.takeMissed()
+	var due []time.Time
+	for _, t := range j.cron.nextFor(j.lastScheduled, horizon.Sub(j.lastScheduled)) {
+		if t.Before(late) {
+			missed = append(missed, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+
+	runs := []*scheduledJob{}
+	catchUp := j.jobConfig.catchUp(missed)
+	if len(missed) > 0 {
+		logger.Info("job missed scheduled runs", "job_id", j.ID(), "missed", len(missed), "catching_up", len(catchUp))
+	}
+	for _, t := range catchUp {
+		runs = append(runs, j.newRun(TriggerCatchUp, t, now))
+	}
+
+	for _, t := range due {
+		runs = append(runs, j.newRun(TriggerSchedule, t, t))
+	}
+
+	if len(runs) > 0 {
+		j.lastScheduled = runs[len(runs)-1].startTime
+	} else if j.lastScheduled.Before(now) {
+		j.lastScheduled = now
+	}
+
+	return runs
+}
+
+// couldNotStart records that r did not start because of err, and returns err.
+func (j *jobMetadata) couldNotStart(r *scheduledJob, err error) error {
+	j.logger.Error(err, "job could not start")
+	j.addResult(r.history(1, time.Now().UTC()), err)
+	return err
 }
 
-func (j *jobMetadata) run(logger logr.Logger, startTime time.Time) func(ctx context.Context) error {
+func (j *jobMetadata) run(r *scheduledJob) func(ctx context.Context) error {
+	logger := j.logger
+	// A run that is waiting for a free worker should still be skipped if the
+	// job was running when the run was due, so check that up front.
+	forbidden := j.forbidden()
+
 	return func(ctx context.Context) error {
-		err := j.shouldRun(startTime)
+		defer j.releaseTurn(r)
+
+		// Runs the scheduler started wait for a worker, during which the
+		// job may have been removed or updated. Those runs are dropped.
+		if r.trigger != TriggerManual && j.current != nil && !j.current(j) {
+			logger.Info("job removed or updated, dropping run", "run_id", r.id, "scheduled_time", r.scheduledTime)
+			return nil
+		}
+
+		if r.trigger.scheduled() {
+			j.updateState(func(state *JobState) {
+				if r.scheduledTime.After(state.LastScheduled) {
+					state.LastScheduled = r.scheduledTime
+				}
+			})
+		}
+
+		if j.locker != nil && r.trigger.scheduled() {
+			locked, err := j.locker.Lock(ctx, j.ID(), r.scheduledTime)
+			if err != nil {
+				return j.couldNotStart(r, fmt.Errorf("failed to lock run: %w", err))
+			}
+
+			if !locked {
+				err := ErrRunClaimed{}
+				logger.Info(err.Error(), "scheduled_time", r.scheduledTime)
+				j.addResult(r.history(1, time.Now().UTC()), err)
+				return err
+			}
+		}
+
+		if forbidden != nil {
+			return j.couldNotStart(r, forbidden)
+		}
+
+		ctx, release, err := j.startRun(ctx, r)
 		if err != nil {
-			logger.Error(err, "job could not start")
-			j.addResult(startTime, err)
+			return j.couldNotStart(r, err)
+		}
+		defer release()
+
+		finish := func(err error) error {
+			j.recordOutcome(err)
+			if j.upstreamDone != nil {
+				j.upstreamDone(r, err)
+			}
 			return err
 		}
 
-		logger.Info("job started", "start_time", startTime.UTC())
-		ctx, cancel := context.WithTimeout(ctx, j.jobConfig.Timeout)
-		defer cancel()
+		for attempt := 1; ; attempt++ {
+			err := j.execute(ctx, r, attempt)
+			if err == nil || ctx.Err() != nil || !j.jobConfig.Retry.shouldRetry(attempt, err) {
+				return finish(err)
+			}
 
-		errChan := make(chan error)
-		go func() {
-			err := j.jobConfig.Func(ctx)
-			if err != nil {
-				logger.Error(err, "job failed")
-			} else {
-				logger.Info("job finished successfully", "end_time", time.Now().UTC())
+			backoff := j.jobConfig.Retry.backoff(attempt)
+			logger.Info("retrying job", "attempt", attempt+1, "backoff", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return finish(err)
 			}
-			errChan <- nil
-		}()
+		}
+	}
+}
+
+// execute runs a single attempt of the job and records its result.
+func (j *jobMetadata) execute(ctx context.Context, r *scheduledJob, attempt int) error {
+	logger := j.logger.WithValues("run_id", r.id, "attempt", attempt)
+	h := r.history(attempt, time.Now().UTC())
+	logger.Info("job started", "scheduled_time", r.scheduledTime, "trigger", r.trigger)
+	j.events.publish(Event{Type: EventRunStarted, JobID: j.ID(), Run: h.runInfo()})
+	j.onStart(h)
+
+	ctx, endSpan := j.tracer.StartRun(ctx, j.jobConfig.Schedule, h.runInfo())
+	result := &runResult{}
+	ctx = context.WithValue(ctx, resultKey{}, result)
+	ctx = context.WithValue(ctx, runInfoKey{}, h.runInfo())
+	ctx = logr.NewContext(ctx, logger)
+
+	cancel := context.CancelFunc(func() {})
+	if j.jobConfig.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, j.jobConfig.Timeout)
+	}
+	defer cancel()
+
+	// The channel is buffered so that the goroutine can exit even if the
+	// run has already timed out or been canceled.
+	errChan := make(chan error, 1)
+	go func() {
+		err := j.call(ctx)
+		var panicked ErrJobPanicked
+		if errors.As(err, &panicked) {
+			logger.Error(err, "job panicked", "stack", string(panicked.Stack))
+		} else if err != nil {
+			logger.Error(err, "job failed")
+		} else {
+			logger.Info("job finished successfully", "end_time", time.Now().UTC())
+		}
+		errChan <- err
+	}()
 
-		select {
-		case <-ctx.Done():
-			cancel()
-			err := ErrJobTimeout{}
+	var err error
+	select {
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrJobTimeout{}
 			logger.Error(err, "job execution timed out")
-			j.addResult(startTime, err)
-			return err
-		case err := <-errChan:
-			j.addResult(startTime, err)
-			return err
+		} else {
+			logger.Error(err, "job run was canceled")
 		}
+	case err = <-errChan:
 	}
+
+	h.result = result.get()
+	j.addResult(h, err)
+	endSpan(h)
+	return err
+}
+
+// call runs the job's function. Unless panic recovery is disabled, a panic is
+// recovered and returned as ErrJobPanicked.
+func (j *jobMetadata) call(ctx context.Context) (err error) {
+	if j.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				err = ErrJobPanicked{Value: r, Stack: debug.Stack()}
+			}
+		}()
+	}
+
+	return j.fn(ctx)
 }
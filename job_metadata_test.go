@@ -0,0 +1,197 @@
+package cronroutine
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobMetadata_nextRunsMisfirePolicy(t *testing.T) {
+	lastScheduled := time.Date(2021, time.January, 3, 14, 0, second, nanosecond, time.UTC)
+	now := time.Date(2021, time.January, 3, 14, 5, 30, nanosecond, time.UTC)
+	horizon := now.Add(3 * time.Minute)
+	upcoming := []time.Time{
+		time.Date(2021, time.January, 3, 14, 6, second, nanosecond, time.UTC),
+		time.Date(2021, time.January, 3, 14, 7, second, nanosecond, time.UTC),
+		time.Date(2021, time.January, 3, 14, 8, second, nanosecond, time.UTC),
+	}
+
+	tests := []struct {
+		name            string
+		policy          MisfirePolicy
+		limit           int
+		expectedCatchUp []int
+		lastScheduled   time.Time
+	}{
+		{
+			name:            "default skips missed runs",
+			policy:          "",
+			lastScheduled:   lastScheduled,
+			expectedCatchUp: nil,
+		},
+		{
+			name:            "skip",
+			policy:          MisfireSkip,
+			lastScheduled:   lastScheduled,
+			expectedCatchUp: nil,
+		},
+		{
+			name:            "run once",
+			policy:          MisfireRunOnce,
+			lastScheduled:   lastScheduled,
+			expectedCatchUp: []int{5},
+		},
+		{
+			name:            "run all",
+			policy:          MisfireRunAll,
+			lastScheduled:   lastScheduled,
+			expectedCatchUp: []int{1, 2, 3, 4, 5},
+		},
+		{
+			name:            "run all with limit",
+			policy:          MisfireRunAll,
+			limit:           2,
+			lastScheduled:   lastScheduled,
+			expectedCatchUp: []int{4, 5},
+		},
+		{
+			name:            "never scheduled",
+			policy:          MisfireRunAll,
+			lastScheduled:   time.Time{},
+			expectedCatchUp: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron("* * * * *")
+			assert.NoError(t, err)
+
+			job := &jobMetadata{
+				jobConfig:     &JobConfig{ID: "test", MisfirePolicy: tt.policy, MisfireLimit: tt.limit},
+				historyStore:  NewMemoryHistoryStore(10),
+				historyLimit:  10,
+				cron:          cron,
+				lastScheduled: tt.lastScheduled,
+				jobRuntime:    newJobRuntime(JobState{}),
+			}
+
+			runs := job.nextRuns(logr.Discard(), now, horizon)
+			assert.Len(t, runs, len(tt.expectedCatchUp)+len(upcoming))
+			for i, run := range runs {
+				if i < len(tt.expectedCatchUp) {
+					assert.Equal(t, TriggerCatchUp, run.trigger)
+					assert.Equal(t, now, run.startTime)
+					assert.Equal(t, lastScheduled.Add(time.Duration(tt.expectedCatchUp[i])*time.Minute), run.scheduledTime)
+					continue
+				}
+
+				expected := upcoming[i-len(tt.expectedCatchUp)]
+				assert.Equal(t, TriggerSchedule, run.trigger)
+				assert.Equal(t, expected, run.startTime)
+				assert.Equal(t, expected, run.scheduledTime)
+			}
+			assert.Equal(t, upcoming[len(upcoming)-1], job.lastScheduled)
+
+			assert.Empty(t, job.nextRuns(logr.Discard(), now, horizon))
+		})
+	}
+}
+
+func TestJobMetadata_nextRunsLateLoop(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2021, time.January, 3, 12, minute, 0, 0, time.UTC) }
+	cron, err := ParseCron("* * * * *")
+	assert.NoError(t, err)
+	job := &jobMetadata{
+		jobConfig:    &JobConfig{ID: "test"},
+		historyStore: NewMemoryHistoryStore(10),
+		historyLimit: 10,
+		cron:         cron,
+		jobRuntime:   newJobRuntime(JobState{}),
+	}
+	scheduled := func(runs []*scheduledJob) []time.Time {
+		var ret []time.Time
+		for _, run := range runs {
+			assert.Equal(t, TriggerSchedule, run.trigger)
+			ret = append(ret, run.scheduledTime)
+		}
+		return ret
+	}
+
+	now := at(0).Add(time.Millisecond)
+	horizon := at(3).Add(-time.Millisecond)
+	assert.Equal(t, []time.Time{at(1), at(2)}, scheduled(job.nextRuns(logr.Discard(), now, horizon)))
+
+	// The loop wakes up just after 12:03, so the 12:03 run is a little late
+	// but not missed.
+	now = at(3).Add(time.Millisecond)
+	assert.Equal(t, []time.Time{at(3), at(4), at(5), at(6)}, scheduled(job.nextRuns(logr.Discard(), now, now.Add(3*time.Minute))))
+
+	// Runs that are later than misfireTolerance are missed.
+	now = at(9).Add(misfireTolerance + time.Millisecond)
+	assert.Equal(t, []time.Time{at(10), at(11), at(12)}, scheduled(job.nextRuns(logr.Discard(), now, now.Add(3*time.Minute))))
+}
+
+func TestJobMetadata_nextRunsMissedTimes(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2021, time.January, 3, 14, minute, 0, 0, time.UTC) }
+	now := at(5).Add(30 * time.Second)
+	newJob := func() *jobMetadata {
+		cron, err := ParseCron("* * * * *")
+		assert.NoError(t, err)
+
+		return &jobMetadata{
+			jobConfig:    &JobConfig{ID: "test", MisfirePolicy: MisfireRunAll},
+			historyStore: NewMemoryHistoryStore(10),
+			historyLimit: 10,
+			cron:         cron,
+			jobRuntime:   newJobRuntime(JobState{}),
+		}
+	}
+	catchUps := func(runs []*scheduledJob) []time.Time {
+		var ret []time.Time
+		for _, run := range runs {
+			if run.trigger == TriggerCatchUp {
+				ret = append(ret, run.scheduledTime)
+			}
+		}
+		return ret
+	}
+
+	t.Run("runs dropped while paused are missed", func(t *testing.T) {
+		job := newJob()
+		// The queue loop handed the runs up to 14:08 to the worker loop
+		// before the job was paused.
+		job.lastScheduled = at(8)
+
+		assert.False(t, job.dropIfPaused(job.newRun(TriggerSchedule, at(3), at(3))))
+		job.setPaused(true)
+		assert.True(t, job.dropIfPaused(job.newRun(TriggerSchedule, at(5), at(5))))
+		assert.True(t, job.dropIfPaused(job.newRun(TriggerSchedule, at(4), at(4))))
+		assert.True(t, job.dropIfPaused(job.newRun(TriggerManual, at(4), at(4))))
+		job.setPaused(false)
+
+		assert.Equal(t, []time.Time{at(4), at(5)}, catchUps(job.nextRuns(logr.Discard(), now, now.Add(3*time.Minute))))
+		assert.Empty(t, catchUps(job.nextRuns(logr.Discard(), now, now.Add(3*time.Minute))))
+	})
+
+	t.Run("manual runs do not hide missed runs", func(t *testing.T) {
+		job := newJob()
+		assert.NoError(t, job.historyStore.Add(&History{jobID: "test", trigger: TriggerSchedule, scheduledAt: at(2)}))
+		assert.NoError(t, job.historyStore.Add(&History{jobID: "test", trigger: TriggerManual, scheduledAt: at(4)}))
+
+		assert.Equal(t, []time.Time{at(3), at(4), at(5)}, catchUps(job.nextRuns(logr.Discard(), now, now.Add(3*time.Minute))))
+	})
+
+	t.Run("saved state is preferred to history", func(t *testing.T) {
+		job := newJob()
+		store, err := NewFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+		assert.NoError(t, err)
+		job.stateStore = store
+		assert.NoError(t, job.historyStore.Add(&History{jobID: "test", trigger: TriggerSchedule, scheduledAt: at(2)}))
+
+		assert.Empty(t, catchUps(job.nextRuns(logr.Discard(), now, now.Add(3*time.Minute))))
+	})
+}
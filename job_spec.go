@@ -0,0 +1,224 @@
+package cronroutine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Handlers maps the names that job specs refer to in their handler field to
+// the functions that run them.
+type Handlers map[string]JobFunc
+
+// JobSpec is the configuration of a job as it is written in a file. Unlike a
+// JobConfig, it names its function instead of holding it.
+type JobSpec struct {
+	ID       string `yaml:"id"`
+	Schedule string `yaml:"schedule"`
+	Handler  string `yaml:"handler"`
+
+	// Timezone is the name of the time zone the schedule is evaluated in,
+	// such as America/New_York. The default is UTC.
+	Timezone string `yaml:"timezone"`
+
+	// Timeout and StartingDeadline are durations such as "30s" or "1h".
+	Timeout          time.Duration `yaml:"timeout"`
+	StartingDeadline time.Duration `yaml:"starting_deadline"`
+
+	ConcurrencyPolicy ConcurrencyPolicy `yaml:"concurrency_policy"`
+	QueueDepth        int               `yaml:"queue_depth"`
+	MisfirePolicy     MisfirePolicy     `yaml:"misfire_policy"`
+	MisfireLimit      int               `yaml:"misfire_limit"`
+	Retry             *RetrySpec        `yaml:"retry"`
+	Group             string            `yaml:"group"`
+	Tags              []string          `yaml:"tags"`
+	Priority          int               `yaml:"priority"`
+}
+
+// RetrySpec is a RetryPolicy as it is written in a file. Every error is
+// retried.
+type RetrySpec struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+	Jitter         float64       `yaml:"jitter"`
+}
+
+// JobSpecError is an error in the spec of one job.
+type JobSpecError struct {
+	// Index is the position of the spec in the document, starting at zero.
+	Index int
+	ID    string
+	Err   error
+}
+
+func (e *JobSpecError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("job %d: %v", e.Index, e.Err)
+	}
+
+	return fmt.Sprintf("job %d (%s): %v", e.Index, e.ID, e.Err)
+}
+
+func (e *JobSpecError) Unwrap() error {
+	return e.Err
+}
+
+// ParseJobSpecs reads a YAML or JSON document with a list of job specs under
+// "jobs", for example:
+//
+//	jobs:
+//	  - id: nightly-report
+//	    schedule: "0 2 * * *"
+//	    timezone: Europe/Berlin
+//	    handler: report
+//	    timeout: 10m
+//	    concurrency_policy: Forbid
+//	    retry:
+//	      max_attempts: 3
+//	      initial_backoff: 30s
+//
+// Fields that a JobSpec does not have are an error. The specs are not
+// validated; JobConfig does that.
+func ParseJobSpecs(r io.Reader) ([]JobSpec, error) {
+	var doc struct {
+		Jobs []JobSpec `yaml:"jobs"`
+	}
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse job specs: %w", err)
+	}
+
+	return doc.Jobs, nil
+}
+
+// JobConfig validates the spec and returns the JobConfig it describes, with
+// the function handlers has for its handler. Its Fingerprint is the name of
+// the handler, so Reconcile treats specs with the same handler as running the
+// same function.
+func (s *JobSpec) JobConfig(handlers Handlers) (JobConfig, error) {
+	var errs []error
+	if s.ID == "" {
+		errs = append(errs, errors.New("id is required"))
+	}
+
+	var location *time.Location
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid timezone: %w", err))
+		}
+		location = loc
+	}
+
+	if _, err := ParseCron(s.Schedule); err != nil {
+		errs = append(errs, fmt.Errorf("invalid schedule %q: %w", s.Schedule, err))
+	}
+
+	fn, ok := handlers[s.Handler]
+	if !ok {
+		errs = append(errs, fmt.Errorf("unknown handler %q", s.Handler))
+	}
+
+	if err := s.ConcurrencyPolicy.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := s.MisfirePolicy.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.Timeout < 0 || s.StartingDeadline < 0 {
+		errs = append(errs, errors.New("timeout and starting deadline can not be negative"))
+	}
+
+	if len(errs) > 0 {
+		return JobConfig{}, errors.Join(errs...)
+	}
+
+	var retry *RetryPolicy
+	if s.Retry != nil {
+		retry = &RetryPolicy{
+			MaxAttempts:    s.Retry.MaxAttempts,
+			InitialBackoff: s.Retry.InitialBackoff,
+			MaxBackoff:     s.Retry.MaxBackoff,
+			Multiplier:     s.Retry.Multiplier,
+			Jitter:         s.Retry.Jitter,
+		}
+	}
+
+	return JobConfig{
+		ID:                s.ID,
+		Schedule:          s.Schedule,
+		Location:          location,
+		Timeout:           s.Timeout,
+		StartingDeadline:  s.StartingDeadline,
+		ConcurrencyPolicy: s.ConcurrencyPolicy,
+		QueueDepth:        s.QueueDepth,
+		MisfirePolicy:     s.MisfirePolicy,
+		MisfireLimit:      s.MisfireLimit,
+		Retry:             retry,
+		Group:             s.Group,
+		Tags:              s.Tags,
+		Priority:          s.Priority,
+		Func:              fn,
+		Fingerprint:       "handler:" + s.Handler,
+	}, nil
+}
+
+// ParseJobConfigs reads job specs with ParseJobSpecs and binds them to
+// handlers. If any spec is invalid, or two have the same ID, the returned error
+// joins a *JobSpecError for every invalid spec.
+func ParseJobConfigs(r io.Reader, handlers Handlers) ([]JobConfig, error) {
+	specs, err := ParseJobSpecs(r)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]JobConfig, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	var errs []error
+	for i := range specs {
+		job, err := specs[i].JobConfig(handlers)
+		if err == nil && seen[job.ID] {
+			err = fmt.Errorf("duplicate id %s", job.ID)
+		}
+		if err != nil {
+			errs = append(errs, &JobSpecError{Index: i, ID: specs[i].ID, Err: err})
+			continue
+		}
+		seen[job.ID] = true
+		jobs = append(jobs, job)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return jobs, nil
+}
+
+// LoadJobs reads job specs with ParseJobConfigs and adds them to s. If any spec
+// is invalid no job is added. Otherwise every job is added, and jobs that
+// AddJob rejects, for example because s already has their ID, are reported as
+// a *JobSpecError.
+func (s *Scheduler) LoadJobs(r io.Reader, handlers Handlers) error {
+	jobs, err := ParseJobConfigs(r, handlers)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, job := range jobs {
+		if err := s.AddJob(job); err != nil {
+			errs = append(errs, &JobSpecError{Index: i, ID: job.ID, Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_LoadJobs(t *testing.T) {
+	t.Parallel()
+	handlers := Handlers{
+		"report": func(ctx context.Context) error { return nil },
+		"sync":   func(ctx context.Context) error { return errors.New("failed") },
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{
+			name: "yaml",
+			doc: `
+jobs:
+  - id: nightly-report
+    schedule: "30 2 * * *"
+    timezone: America/New_York
+    handler: report
+    timeout: 10m
+    concurrency_policy: Forbid
+  - id: sync
+    schedule: "@hourly"
+    handler: sync
+    starting_deadline: 30s
+    retry:
+      max_attempts: 3
+      initial_backoff: 10ms
+`,
+		},
+		{
+			name: "json",
+			doc: `{"jobs": [
+  {"id": "nightly-report", "schedule": "30 2 * * *", "timezone": "America/New_York",
+   "handler": "report", "timeout": "10m", "concurrency_policy": "Forbid"},
+  {"id": "sync", "schedule": "@hourly", "handler": "sync", "starting_deadline": "30s",
+   "retry": {"max_attempts": 3, "initial_backoff": "10ms"}}
+]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			scheduler := newTestScheduler(1)
+			assert.NoError(t, scheduler.LoadJobs(strings.NewReader(tt.doc), handlers))
+
+			report, err := scheduler.GetJob("nightly-report")
+			assert.NoError(t, err)
+			assert.Equal(t, "America/New_York", report.Location().String())
+			assert.Equal(t, 10*time.Minute, report.Timeout())
+			assert.Equal(t, ConcurrencyForbid, report.ConcurrencyPolicy())
+			next := report.NextRun().In(report.Location())
+			assert.Equal(t, 2, next.Hour())
+			assert.Equal(t, 30, next.Minute())
+
+			sync, err := scheduler.GetJob("sync")
+			assert.NoError(t, err)
+			assert.Equal(t, time.UTC, sync.Location())
+			assert.Equal(t, 30*time.Second, sync.StartingDeadline())
+			assert.Equal(t, 3, sync.Retry().MaxAttempts)
+			assert.Equal(t, 10*time.Millisecond, sync.Retry().InitialBackoff)
+			assert.EqualError(t, scheduler.TriggerJob(context.Background(), "sync"), "failed")
+		})
+	}
+}
+
+func TestScheduler_LoadJobs_errors(t *testing.T) {
+	t.Parallel()
+	handlers := Handlers{"report": func(ctx context.Context) error { return nil }}
+	doc := `
+jobs:
+  - id: ok
+    schedule: "0 0 * * *"
+    handler: report
+  - id: bad
+    schedule: "0 25 * * *"
+    timezone: Nowhere/Special
+    handler: missing
+    concurrency_policy: Sometimes
+  - schedule: "0 0 * * *"
+    handler: report
+  - id: ok
+    schedule: "0 1 * * *"
+    handler: report
+`
+
+	scheduler := newTestScheduler(1)
+	err := scheduler.LoadJobs(strings.NewReader(doc), handlers)
+	assert.EqualError(t, err, "job 1 (bad): invalid timezone: unknown time zone Nowhere/Special\n"+
+		"invalid schedule \"0 25 * * *\": failed to parse hour: failed to parse number: value 25 is not in range 0-23\n"+
+		"unknown handler \"missing\"\n"+
+		"unknown concurrency policy \"Sometimes\"\n"+
+		"job 2: id is required\n"+
+		"job 3 (ok): duplicate id ok")
+	assert.Empty(t, scheduler.ListJobs())
+
+	var specErr *JobSpecError
+	assert.True(t, errors.As(err, &specErr))
+	assert.Equal(t, "bad", specErr.ID)
+
+	err = scheduler.LoadJobs(strings.NewReader("jobs:\n  - id: x\n    schedul: \"* * * * *\"\n"), handlers)
+	assert.ErrorContains(t, err, "field schedul not found")
+}
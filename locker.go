@@ -0,0 +1,81 @@
+package cronroutine
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Locker makes sure that each fire time of a job is run by only one Scheduler,
+// for example when several replicas of a service share the same jobs. Before a
+// scheduled or catch-up run starts, the Scheduler locks the job's scheduled
+// time and records the run as skipped with ErrRunClaimed if it was already
+// locked. Manual runs are not locked. Implementations must be safe for
+// concurrent use.
+type Locker interface {
+	// Lock claims the run of jobID scheduled for scheduledTime. It reports
+	// false if that run has already been claimed.
+	Lock(ctx context.Context, jobID string, scheduledTime time.Time) (bool, error)
+}
+
+// lockExpiry is how long the lockers in this package keep a claim. A fire time
+// that is run again after that, for example when a Scheduler catches up on
+// runs it missed while it was down for longer, can be claimed again.
+const lockExpiry = 24 * time.Hour
+
+// lockKey identifies a fire time of a job.
+type lockKey struct {
+	jobID         string
+	scheduledTime int64
+}
+
+func newLockKey(jobID string, scheduledTime time.Time) lockKey {
+	return lockKey{jobID: jobID, scheduledTime: scheduledTime.UnixNano()}
+}
+
+// MemoryLocker is a Locker for Schedulers in the same process. Claims are kept
+// for a day.
+type MemoryLocker struct {
+	lock    sync.Mutex
+	expiry  time.Duration
+	claimed map[lockKey]time.Time
+	pruned  time.Time
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		expiry:  lockExpiry,
+		claimed: make(map[lockKey]time.Time),
+	}
+}
+
+func (l *MemoryLocker) Lock(ctx context.Context, jobID string, scheduledTime time.Time) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.pruneLocked(now)
+
+	key := newLockKey(jobID, scheduledTime)
+	if expires, ok := l.claimed[key]; ok && now.Before(expires) {
+		return false, nil
+	}
+
+	l.claimed[key] = now.Add(l.expiry)
+	return true, nil
+}
+
+// pruneLocked forgets expired claims, at most once a minute so that claiming
+// stays cheap when there are many.
+func (l *MemoryLocker) pruneLocked(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+
+	for key, expires := range l.claimed {
+		if !now.Before(expires) {
+			delete(l.claimed, key)
+		}
+	}
+	l.pruned = now
+}
@@ -0,0 +1,116 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// FileLocker is a Locker that coordinates Schedulers through files in a
+// directory, which can be on a volume shared by several hosts as long as it
+// supports flock. Each job has a lock file listing the scheduled times that
+// were claimed and when the claims expire. Claims are kept for a day.
+type FileLocker struct {
+	dir    string
+	expiry time.Duration
+}
+
+// flockRetryInterval is how often Lock tries again to lock a lock file that
+// another process holds.
+const flockRetryInterval = 10 * time.Millisecond
+
+// NewFileLocker returns a FileLocker that keeps its lock files in dir, creating
+// the directory if needed.
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	return &FileLocker{dir: dir, expiry: lockExpiry}, nil
+}
+
+// Lock waits until ctx is done for other processes to release the job's lock
+// file.
+func (l *FileLocker) Lock(ctx context.Context, jobID string, scheduledTime time.Time) (bool, error) {
+	path := filepath.Join(l.dir, url.PathEscape(jobID)+".lock")
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	defer f.Close()
+
+	if err := flock(ctx, f); err != nil {
+		return false, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return false, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	// Each line is a claimed scheduled time and when the claim expires.
+	now := time.Now()
+	claim := scheduledTime.UTC().Format(time.RFC3339Nano)
+	var buf strings.Builder
+	for _, line := range strings.Split(string(b), "\n") {
+		claimed, expiry, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+
+		expires, err := time.Parse(time.RFC3339Nano, expiry)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse lock file %s: %w", path, err)
+		}
+		if !now.Before(expires) {
+			continue
+		}
+		if claimed == claim {
+			return false, nil
+		}
+
+		buf.WriteString(line + "\n")
+	}
+	buf.WriteString(claim + " " + now.Add(l.expiry).UTC().Format(time.RFC3339Nano) + "\n")
+
+	if err := f.Truncate(0); err != nil {
+		return false, fmt.Errorf("failed to truncate lock file: %w", err)
+	}
+
+	if _, err := f.WriteAt([]byte(buf.String()), 0); err != nil {
+		return false, fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return false, fmt.Errorf("failed to sync lock file: %w", err)
+	}
+
+	return true, nil
+}
+
+// flock locks f exclusively, waiting until ctx is done for other processes to
+// release it.
+func flock(ctx context.Context, f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(flockRetryInterval):
+		}
+	}
+}
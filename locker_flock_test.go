@@ -0,0 +1,44 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package cronroutine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLocker(t *testing.T) {
+	locker, err := NewFileLocker(t.TempDir())
+	assert.NoError(t, err)
+
+	testLocker(t, locker)
+
+	locker, err = NewFileLocker(t.TempDir())
+	assert.NoError(t, err)
+	locker.expiry = 10 * time.Millisecond
+	testLockerExpiry(t, locker)
+}
+
+func TestFileLocker_canceled(t *testing.T) {
+	dir := t.TempDir()
+	locker, err := NewFileLocker(dir)
+	assert.NoError(t, err)
+
+	// Another process holds the job's lock file.
+	f, err := os.OpenFile(filepath.Join(dir, "test.lock"), os.O_RDWR|os.O_CREATE, 0o644)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	locked, err := locker.Lock(ctx, "test", time.Now())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, locked)
+}
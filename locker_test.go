@@ -0,0 +1,110 @@
+package cronroutine
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLocker(t *testing.T, locker Locker) {
+	ctx := context.Background()
+	first := time.Date(2021, time.January, 3, 14, 0, second, nanosecond, time.UTC)
+	later := first.Add(time.Minute)
+
+	locked, err := locker.Lock(ctx, "a/b", first)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	locked, err = locker.Lock(ctx, "a/b", first)
+	assert.NoError(t, err)
+	assert.False(t, locked, "the same fire time can only be locked once")
+
+	locked, err = locker.Lock(ctx, "other", first)
+	assert.NoError(t, err)
+	assert.True(t, locked, "other jobs are locked separately")
+
+	locked, err = locker.Lock(ctx, "a/b", later)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	locked, err = locker.Lock(ctx, "a/b", first.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, locked, "earlier fire times can be locked after a later one, as catch-up runs may be")
+
+	locked, err = locker.Lock(ctx, "a/b", later)
+	assert.NoError(t, err)
+	assert.False(t, locked)
+}
+
+// testLockerExpiry checks that claims of locker, which keeps them for 10ms,
+// expire.
+func testLockerExpiry(t *testing.T, locker Locker) {
+	ctx := context.Background()
+	scheduledTime := time.Date(2021, time.January, 3, 14, 0, 0, 0, time.UTC)
+
+	locked, err := locker.Lock(ctx, "test", scheduledTime)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	time.Sleep(20 * time.Millisecond)
+	locked, err = locker.Lock(ctx, "test", scheduledTime)
+	assert.NoError(t, err)
+	assert.True(t, locked, "expired claims can be claimed again")
+}
+
+func TestMemoryLocker(t *testing.T) {
+	testLocker(t, NewMemoryLocker())
+
+	locker := NewMemoryLocker()
+	locker.expiry = 10 * time.Millisecond
+	testLockerExpiry(t, locker)
+}
+
+func TestScheduler_lockerRunsFireTimeOnce(t *testing.T) {
+	t.Parallel()
+	locker := NewMemoryLocker()
+	var jobsRun atomic.Uint64
+
+	replicas := make([]*Scheduler, 0, 3)
+	for i := 0; i < 3; i++ {
+		cfg := DefaultSchedulerConfig()
+		cfg.Locker = locker
+		scheduler := StartNewScheduler(cfg)
+		err := scheduler.AddJob(JobConfig{
+			ID:                "test-0",
+			Schedule:          "0 0 1 1 *",
+			ConcurrencyPolicy: ConcurrencyAllow,
+			Func: func(ctx context.Context) error {
+				jobsRun.Add(1)
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+		replicas = append(replicas, scheduler)
+	}
+
+	scheduledTime := time.Now().UTC().Truncate(time.Minute)
+	for i, scheduler := range replicas {
+		job, err := scheduler.getJobMetadata("test-0")
+		assert.NoError(t, err)
+		err = job.run(job.newRun(TriggerSchedule, scheduledTime, scheduledTime))(context.Background())
+		if i == 0 {
+			assert.NoError(t, err)
+			continue
+		}
+
+		assert.Equal(t, ErrRunClaimed{}, err)
+		history := job.History()
+		assert.Len(t, history, 1)
+		assert.Equal(t, RunSkipped, history[0].Status())
+	}
+	assert.Equal(t, 1, int(jobsRun.Load()))
+
+	for _, scheduler := range replicas {
+		assert.NoError(t, scheduler.TriggerJob(context.Background(), "test-0"))
+	}
+	assert.Equal(t, 4, int(jobsRun.Load()), "manual runs are not locked")
+}
@@ -0,0 +1,72 @@
+package cronroutine
+
+import "sync"
+
+// Metrics receives measurements from a Scheduler. Its methods are called from
+// the goroutines that run jobs, so they must be safe for concurrent use and
+// return quickly.
+type Metrics interface {
+	// RunFinished is called with the history of every attempt of a run once
+	// it has finished, including runs that were skipped.
+	RunFinished(h *History)
+
+	// QueueDepth is called whenever the number of runs that are due but
+	// waiting for a free worker changes.
+	QueueDepth(n int)
+
+	// WorkersBusy is called whenever the number of workers running a job
+	// changes, along with the size of the worker pool.
+	WorkersBusy(busy, total int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) RunFinished(*History) {}
+func (nopMetrics) QueueDepth(int)       {}
+func (nopMetrics) WorkersBusy(int, int) {}
+
+// poolMetrics tracks how busy the worker pool is and reports it to Metrics.
+// The counts are reported under a lock so that they reach Metrics in the order
+// they changed.
+type poolMetrics struct {
+	lock    sync.Mutex
+	metrics Metrics
+	workers int
+	queued  int
+	busy    int
+}
+
+func (p *poolMetrics) queue() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.queued++
+	p.metrics.QueueDepth(p.queued)
+}
+
+// dequeue is called when a queued run is dropped without running.
+func (p *poolMetrics) dequeue() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.queued--
+	p.metrics.QueueDepth(p.queued)
+}
+
+func (p *poolMetrics) start() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.queued--
+	p.busy++
+	p.metrics.QueueDepth(p.queued)
+	p.metrics.WorkersBusy(p.busy, p.workers)
+}
+
+func (p *poolMetrics) finish() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.busy--
+	p.metrics.WorkersBusy(p.busy, p.workers)
+}
@@ -0,0 +1,194 @@
+package cronroutine
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsBuckets are the upper bounds, in seconds, of the histogram buckets
+// for run durations and lag. Cron jobs run for anywhere between milliseconds
+// and hours, so the buckets cover that whole range.
+var metricsBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 600, 1800, 3600,
+}
+
+// PrometheusMetrics is a Metrics that keeps its measurements in memory and
+// serves them in the Prometheus text exposition format. It implements
+// http.Handler, so it can be mounted on the path Prometheus scrapes.
+type PrometheusMetrics struct {
+	lock        sync.Mutex
+	runs        map[runCountKey]uint64
+	durations   map[string]*histogram
+	lags        map[string]*histogram
+	lastSuccess map[string]time.Time
+	queueDepth  int
+	workersBusy int
+	workers     int
+}
+
+type runCountKey struct {
+	jobID  string
+	status RunStatus
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range metricsBuckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		runs:        make(map[runCountKey]uint64),
+		durations:   make(map[string]*histogram),
+		lags:        make(map[string]*histogram),
+		lastSuccess: make(map[string]time.Time),
+	}
+}
+
+func (m *PrometheusMetrics) RunFinished(h *History) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.runs[runCountKey{jobID: h.jobID, status: h.status}]++
+	if h.status == RunSkipped {
+		return
+	}
+
+	observe(m.durations, h.jobID, h.Duration())
+	// Retries start after a backoff, so only the first attempt says how late
+	// the run was.
+	if h.attempt <= 1 {
+		observe(m.lags, h.jobID, h.Lag())
+	}
+
+	if h.status == RunSucceeded {
+		m.lastSuccess[h.jobID] = h.finishedAt
+	}
+}
+
+func observe(histograms map[string]*histogram, jobID string, d time.Duration) {
+	h, ok := histograms[jobID]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(metricsBuckets))}
+		histograms[jobID] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (m *PrometheusMetrics) QueueDepth(n int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.queueDepth = n
+}
+
+func (m *PrometheusMetrics) WorkersBusy(busy, total int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.workersBusy = busy
+	m.workers = total
+}
+
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	bw := bufio.NewWriter(w)
+	m.write(bw)
+	bw.Flush()
+}
+
+func (m *PrometheusMetrics) write(w *bufio.Writer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	writeHeader(w, "cronroutine_runs_total", "counter", "Number of finished runs of each job by status.")
+	keys := make([]runCountKey, 0, len(m.runs))
+	for key := range m.runs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].jobID != keys[j].jobID {
+			return keys[i].jobID < keys[j].jobID
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "cronroutine_runs_total{job_id=%s,status=%s} %d\n",
+			quoteLabel(key.jobID), quoteLabel(string(key.status)), m.runs[key])
+	}
+
+	writeHistograms(w, "cronroutine_run_duration_seconds", "How long runs of each job took.", m.durations)
+	writeHistograms(w, "cronroutine_run_lag_seconds", "How long after their scheduled time runs of each job started.", m.lags)
+
+	writeHeader(w, "cronroutine_last_success_timestamp_seconds", "gauge", "When each job last finished successfully, as a Unix timestamp.")
+	for _, jobID := range sortedKeys(m.lastSuccess) {
+		fmt.Fprintf(w, "cronroutine_last_success_timestamp_seconds{job_id=%s} %s\n",
+			quoteLabel(jobID), formatFloat(float64(m.lastSuccess[jobID].UnixNano())/1e9))
+	}
+
+	writeHeader(w, "cronroutine_queue_depth", "gauge", "Number of runs that are due but waiting for a free worker.")
+	fmt.Fprintf(w, "cronroutine_queue_depth %d\n", m.queueDepth)
+
+	writeHeader(w, "cronroutine_workers_busy", "gauge", "Number of workers running a job.")
+	fmt.Fprintf(w, "cronroutine_workers_busy %d\n", m.workersBusy)
+
+	writeHeader(w, "cronroutine_workers", "gauge", "Size of the worker pool.")
+	fmt.Fprintf(w, "cronroutine_workers %d\n", m.workers)
+}
+
+func writeHeader(w *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeHistograms(w *bufio.Writer, name, help string, histograms map[string]*histogram) {
+	writeHeader(w, name, "histogram", help)
+	for _, jobID := range sortedKeys(histograms) {
+		h := histograms[jobID]
+		job := quoteLabel(jobID)
+		for i, bound := range metricsBuckets {
+			fmt.Fprintf(w, "%s_bucket{job_id=%s,le=\"%s\"} %d\n", name, job, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{job_id=%s,le=\"+Inf\"} %d\n", name, job, h.count)
+		fmt.Fprintf(w, "%s_sum{job_id=%s} %s\n", name, job, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count{job_id=%s} %d\n", name, job, h.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
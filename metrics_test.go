@@ -0,0 +1,127 @@
+package cronroutine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+	metrics := NewPrometheusMetrics()
+	cfg := DefaultSchedulerConfig()
+	cfg.WorkerCount = 3
+	cfg.Metrics = metrics
+	scheduler := StartNewScheduler(cfg)
+
+	fail := true
+	err := scheduler.AddJob(JobConfig{
+		ID:                `test-"0"`,
+		Schedule:          "0 0 1 1 *",
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Func: func(ctx context.Context) error {
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Error(t, scheduler.TriggerJob(context.Background(), `test-"0"`))
+	fail = false
+	assert.NoError(t, scheduler.TriggerJob(context.Background(), `test-"0"`))
+	metrics.RunFinished(&History{jobID: "test-1", status: RunSkipped, attempt: 1})
+
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain; version=0.0.4")
+
+	b, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	body := string(b)
+
+	assert.Contains(t, body, "# TYPE cronroutine_runs_total counter\n")
+	assert.Contains(t, body, `cronroutine_runs_total{job_id="test-\"0\"",status="Failed"} 1`+"\n")
+	assert.Contains(t, body, `cronroutine_runs_total{job_id="test-\"0\"",status="Succeeded"} 1`+"\n")
+	assert.Contains(t, body, `cronroutine_runs_total{job_id="test-1",status="Skipped"} 1`+"\n")
+
+	assert.Contains(t, body, "# TYPE cronroutine_run_duration_seconds histogram\n")
+	assert.Contains(t, body, `cronroutine_run_duration_seconds_bucket{job_id="test-\"0\"",le="3600"} 2`+"\n")
+	assert.Contains(t, body, `cronroutine_run_duration_seconds_bucket{job_id="test-\"0\"",le="+Inf"} 2`+"\n")
+	assert.Contains(t, body, `cronroutine_run_duration_seconds_count{job_id="test-\"0\""} 2`+"\n")
+	assert.Contains(t, body, `cronroutine_run_lag_seconds_count{job_id="test-\"0\""} 2`+"\n")
+	assert.NotContains(t, body, `cronroutine_run_duration_seconds_count{job_id="test-1"}`, "skipped runs have no duration")
+
+	job, err := scheduler.GetJob(`test-"0"`)
+	assert.NoError(t, err)
+	lastSuccess := job.History()[1].FinishedAt()
+	assert.Contains(t, body, `cronroutine_last_success_timestamp_seconds{job_id="test-\"0\""} `+
+		formatFloat(float64(lastSuccess.UnixNano())/1e9)+"\n")
+
+	assert.Contains(t, body, "cronroutine_queue_depth 0\n")
+	assert.Contains(t, body, "cronroutine_workers_busy 0\n")
+	assert.Contains(t, body, "cronroutine_workers 3\n")
+}
+
+type recordingMetrics struct {
+	nopMetrics
+	queueDepths chan int
+	busy        chan int
+}
+
+func (m *recordingMetrics) QueueDepth(n int)            { m.queueDepths <- n }
+func (m *recordingMetrics) WorkersBusy(busy, total int) { m.busy <- busy }
+
+func TestPoolMetrics(t *testing.T) {
+	metrics := &recordingMetrics{queueDepths: make(chan int, 10), busy: make(chan int, 10)}
+	pool := &poolMetrics{metrics: metrics, workers: 1}
+
+	pool.queue()
+	pool.queue()
+	pool.start()
+	pool.dequeue()
+	pool.finish()
+
+	assert.Equal(t, []int{1, 2, 1, 0}, drain(metrics.queueDepths))
+	assert.Equal(t, []int{1, 0}, drain(metrics.busy))
+}
+
+func drain(c chan int) []int {
+	close(c)
+	values := []int{}
+	for v := range c {
+		values = append(values, v)
+	}
+
+	return values
+}
+
+func TestScheduler_manualRunsUseWorkers(t *testing.T) {
+	t.Parallel()
+	metrics := &recordingMetrics{queueDepths: make(chan int, 10), busy: make(chan int, 10)}
+	cfg := DefaultSchedulerConfig()
+	cfg.WorkerCount = 1
+	cfg.Metrics = metrics
+	scheduler := StartNewScheduler(cfg)
+
+	assert.NoError(t, scheduler.AddJob(JobConfig{
+		ID:       "test-0",
+		Schedule: "0 0 1 1 *",
+		Func:     func(ctx context.Context) error { return nil },
+	}))
+	assert.NoError(t, scheduler.TriggerJob(context.Background(), "test-0"))
+
+	assert.Equal(t, []int{1, 0}, drain(metrics.queueDepths))
+	assert.Equal(t, []int{0, 1, 0}, drain(metrics.busy))
+}
@@ -0,0 +1,91 @@
+package cronroutine
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// JobFunc is the function a job runs.
+type JobFunc func(ctx context.Context) error
+
+// Middleware wraps a JobFunc, for example to add tracing, metrics or values to
+// the context of every run.
+type Middleware func(next JobFunc) JobFunc
+
+// chain wraps fn in middleware so that the first middleware is the outermost.
+func chain(fn JobFunc, middleware ...Middleware) JobFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		fn = middleware[i](fn)
+	}
+
+	return fn
+}
+
+// Hooks are called at points in the life of a job run. Each attempt of a run
+// starts and then either succeeds or fails, while a run that never starts is
+// skipped. Any of the hooks may be nil. Hooks are called synchronously from
+// the run, so they should return quickly. A panic in a hook is recovered and
+// logged unless SchedulerConfig.DisablePanicRecovery is set.
+type Hooks struct {
+	OnStart   func(job *Job, run *RunInfo)
+	OnSuccess func(job *Job, run *RunInfo)
+	OnFailure func(job *Job, run *RunInfo, err error)
+	OnSkip    func(job *Job, run *RunInfo, err error)
+}
+
+func (j *jobMetadata) hasHooks() bool {
+	for _, h := range j.hooks {
+		if h.OnStart != nil || h.OnSuccess != nil || h.OnFailure != nil || h.OnSkip != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (j *jobMetadata) onStart(h *History) {
+	if !j.hasHooks() {
+		return
+	}
+
+	job, run := j.Job(), h.runInfo()
+	for _, hooks := range j.hooks {
+		if hooks.OnStart != nil {
+			j.callHook("OnStart", func() { hooks.OnStart(job, run) })
+		}
+	}
+}
+
+func (j *jobMetadata) onFinish(h *History) {
+	if !j.hasHooks() {
+		return
+	}
+
+	job, run := j.Job(), h.runInfo()
+	for _, hooks := range j.hooks {
+		switch {
+		case h.status == RunSucceeded && hooks.OnSuccess != nil:
+			j.callHook("OnSuccess", func() { hooks.OnSuccess(job, run) })
+		case h.status == RunSkipped && hooks.OnSkip != nil:
+			j.callHook("OnSkip", func() { hooks.OnSkip(job, run, h.err) })
+		case h.status != RunSucceeded && h.status != RunSkipped && hooks.OnFailure != nil:
+			j.callHook("OnFailure", func() { hooks.OnFailure(job, run, h.err) })
+		}
+	}
+}
+
+// callHook calls a hook. Unless panic recovery is disabled, a panic in the hook
+// is recovered and logged like one in a job's function, so that it does not
+// crash the process or stop the other hooks.
+func (j *jobMetadata) callHook(name string, call func()) {
+	if j.recoverPanics {
+		defer func() {
+			if r := recover(); r != nil {
+				j.logger.Error(fmt.Errorf("hook panicked: %v", r), "hook panicked", "hook", name, "stack", string(debug.Stack()))
+			}
+		}()
+	}
+
+	call()
+}
@@ -0,0 +1,55 @@
+package cronroutine
+
+import (
+	"fmt"
+	"time"
+)
+
+// MisfirePolicy determines what happens to fire times that were missed because
+// the scheduler was not running, was running behind, or the job was paused.
+type MisfirePolicy string
+
+const (
+	// MisfireSkip drops all missed fire times. This is the default.
+	MisfireSkip MisfirePolicy = "Skip"
+
+	// MisfireRunOnce starts a single run for all missed fire times.
+	MisfireRunOnce MisfirePolicy = "RunOnce"
+
+	// MisfireRunAll starts one run for every missed fire time, up to
+	// JobConfig.MisfireLimit runs.
+	MisfireRunAll MisfirePolicy = "RunAll"
+)
+
+// misfireTolerance is how late a fire time can be handed to the worker loop and
+// still be run as scheduled instead of being treated as missed.
+const misfireTolerance = 5 * time.Second
+
+// validate returns an error if p is not one of the policies above. The empty
+// policy is valid and selects MisfireSkip.
+func (p MisfirePolicy) validate() error {
+	switch p {
+	case "", MisfireSkip, MisfireRunOnce, MisfireRunAll:
+		return nil
+	default:
+		return fmt.Errorf("unknown misfire policy %q", p)
+	}
+}
+
+// catchUp returns the missed fire times that should still be run according to
+// the job's misfire policy.
+func (c *JobConfig) catchUp(missed []time.Time) []time.Time {
+	switch c.MisfirePolicy {
+	case MisfireRunOnce:
+		if len(missed) > 0 {
+			return missed[len(missed)-1:]
+		}
+	case MisfireRunAll:
+		if c.MisfireLimit > 0 && len(missed) > c.MisfireLimit {
+			return missed[len(missed)-c.MisfireLimit:]
+		}
+		return missed
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+// Package otelcronroutine traces the runs of a cronroutine.Scheduler with
+// OpenTelemetry. Set SchedulerConfig.Tracer to a Tracer to use it.
+package otelcronroutine
+
+import (
+	"context"
+	"time"
+
+	"github.com/drewgonzales360/cronroutine"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/drewgonzales360/cronroutine/otelcronroutine"
+
+// Tracer records a span for every attempt of a run. The span is named after
+// the job, and it is a child of any span in the context the run started with,
+// such as the caller's span when the job is triggered manually. The job's
+// function gets the span through its context.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a Tracer that creates its spans with provider. If provider
+// is nil, the global TracerProvider is used.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{tracer: provider.Tracer(tracerName)}
+}
+
+func (t *Tracer) StartRun(ctx context.Context, schedule string, run *cronroutine.RunInfo) (context.Context, func(h *cronroutine.History)) {
+	ctx, span := t.tracer.Start(ctx, run.JobID,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("cronroutine.job.id", run.JobID),
+			attribute.String("cronroutine.job.schedule", schedule),
+			attribute.String("cronroutine.run.id", run.RunID),
+			attribute.String("cronroutine.run.trigger", string(run.Trigger)),
+			attribute.String("cronroutine.run.scheduled_time", run.ScheduledTime.Format(time.RFC3339)),
+			attribute.Int("cronroutine.run.attempt", run.Attempt),
+		),
+	)
+
+	return ctx, func(h *cronroutine.History) { endSpan(span, h) }
+}
+
+// endSpan records the outcome of the attempt on its span and ends it.
+func endSpan(span trace.Span, h *cronroutine.History) {
+	span.SetAttributes(attribute.String("cronroutine.run.status", string(h.Status())))
+	if err := h.Error(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End(trace.WithTimestamp(h.FinishedAt()))
+}
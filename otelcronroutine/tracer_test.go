@@ -0,0 +1,79 @@
+package otelcronroutine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drewgonzales360/cronroutine"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	cfg := cronroutine.DefaultSchedulerConfig()
+	cfg.Tracer = NewTracer(provider)
+	scheduler := cronroutine.StartNewScheduler(cfg)
+
+	spanIDs := []trace.SpanID{}
+	err := scheduler.AddJob(cronroutine.JobConfig{
+		ID:                "test-0",
+		Schedule:          "0 0 1 1 *",
+		ConcurrencyPolicy: cronroutine.ConcurrencyForbid,
+		Retry:             &cronroutine.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		Func: func(ctx context.Context) error {
+			spanIDs = append(spanIDs, trace.SpanContextFromContext(ctx).SpanID())
+			if len(spanIDs) == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	assert.NoError(t, scheduler.TriggerJob(ctx, "test-0"))
+	parent.End()
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	runs := spans[:2]
+	assert.Equal(t, "parent", spans[2].Name)
+
+	history, err := scheduler.GetJob("test-0")
+	assert.NoError(t, err)
+	h := history.History()
+	assert.Len(t, h, 2)
+
+	for i, span := range runs {
+		assert.Equal(t, "test-0", span.Name)
+		assert.Equal(t, spanIDs[i], span.SpanContext.SpanID(), "the span is in the job's context")
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, attr := range span.Attributes {
+			attrs[attr.Key] = attr.Value
+		}
+		assert.Equal(t, "test-0", attrs["cronroutine.job.id"].AsString())
+		assert.Equal(t, "0 0 1 1 *", attrs["cronroutine.job.schedule"].AsString())
+		assert.Equal(t, h[i].RunID(), attrs["cronroutine.run.id"].AsString())
+		assert.Equal(t, string(cronroutine.TriggerManual), attrs["cronroutine.run.trigger"].AsString())
+		assert.Equal(t, h[i].ScheduledAt().Format(time.RFC3339), attrs["cronroutine.run.scheduled_time"].AsString())
+		assert.Equal(t, int64(i+1), attrs["cronroutine.run.attempt"].AsInt64())
+		assert.Equal(t, string(h[i].Status()), attrs["cronroutine.run.status"].AsString())
+	}
+
+	assert.Equal(t, codes.Error, runs[0].Status.Code)
+	assert.Equal(t, "failed", runs[0].Status.Description)
+	assert.Len(t, runs[0].Events, 1, "the error is recorded")
+	assert.Equal(t, codes.Ok, runs[1].Status.Code)
+}
@@ -0,0 +1,233 @@
+package cronroutine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// ReconcileResult lists the IDs of the jobs Reconcile added, updated, removed
+// and left alone.
+type ReconcileResult struct {
+	Added     []string
+	Updated   []string
+	Removed   []string
+	Unchanged []string
+}
+
+// Reconcile makes the jobs of the scheduler match jobs. Jobs the scheduler
+// does not have are added, jobs it has that are not in jobs are removed, and
+// jobs whose configuration changed are updated like UpdateJob does. Jobs
+// whose configuration is the same are left alone, so their scheduled runs
+// are not disturbed.
+//
+// Configurations are compared field by field. Functions can not be compared,
+// so Func, Middleware, Hooks and Retry.Retryable are only treated as the same
+// when both configurations have the same non-empty Fingerprint. Jobs without
+// one are updated every time.
+//
+// The change is atomic: if any job is invalid, two have the same ID or their
+// dependencies form a cycle, nothing is changed. The only errors that can
+// happen after the jobs are changed are from deleting the state of removed
+// jobs, which are still removed.
+func (s *Scheduler) Reconcile(jobs []JobConfig) (*ReconcileResult, error) {
+	s.jobsLock.Lock()
+	defer s.jobsLock.Unlock()
+
+	result := &ReconcileResult{}
+	changed := make(map[string]*jobMetadata, len(jobs))
+	seen := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		if seen[job.ID] {
+			return nil, fmt.Errorf("job with ID %s is given more than once", job.ID)
+		}
+		seen[job.ID] = true
+
+		existing, ok := s.jobs[job.ID]
+		var metadata *jobMetadata
+		var err error
+		switch {
+		case !ok:
+			metadata, err = s.newJob(job)
+			result.Added = append(result.Added, job.ID)
+		case !sameJobConfig(existing.jobConfig, &job):
+			metadata, err = s.updatedJob(existing, job)
+			result.Updated = append(result.Updated, job.ID)
+		default:
+			result.Unchanged = append(result.Unchanged, job.ID)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid job %s: %w", job.ID, err)
+		}
+		changed[job.ID] = metadata
+	}
+
+	configs := make(map[string]*JobConfig, len(jobs))
+	ids := make([]string, 0, len(jobs))
+	for i := range jobs {
+		configs[jobs[i].ID] = &jobs[i]
+		ids = append(ids, jobs[i].ID)
+	}
+	cycle := findCycle(ids, func(id string) []Dependency {
+		if job, ok := configs[id]; ok {
+			return job.DependsOn
+		}
+		return nil
+	})
+	if cycle != nil {
+		return nil, ErrDependencyCycle{Cycle: cycle}
+	}
+
+	for id := range s.jobs {
+		if !seen[id] {
+			result.Removed = append(result.Removed, id)
+		}
+	}
+	slices.Sort(result.Removed)
+
+	for _, id := range result.Added {
+		s.jobs[id] = changed[id]
+		s.events.publish(Event{Type: EventJobAdded, JobID: id})
+	}
+	for _, id := range result.Updated {
+		s.jobs[id] = changed[id]
+		s.events.publish(Event{Type: EventJobUpdated, JobID: id})
+	}
+	var errs []error
+	for _, id := range result.Removed {
+		if err := s.removeJob(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.wakeQueueLoop()
+
+	return result, errors.Join(errs...)
+}
+
+// sameJobConfig reports whether a and b configure a job the same way.
+func sameJobConfig(a, b *JobConfig) bool {
+	return a.ID == b.ID &&
+		a.Schedule == b.Schedule &&
+		locationName(a.Location) == locationName(b.Location) &&
+		a.Timeout == b.Timeout &&
+		a.StartingDeadline == b.StartingDeadline &&
+		a.concurrencyPolicy() == b.concurrencyPolicy() &&
+		a.QueueDepth == b.QueueDepth &&
+		a.MisfirePolicy == b.MisfirePolicy &&
+		a.MisfireLimit == b.MisfireLimit &&
+		sameRetryPolicy(a.Retry, b.Retry) &&
+		a.Group == b.Group &&
+		slices.Equal(a.Tags, b.Tags) &&
+		a.Priority == b.Priority &&
+		slices.Equal(a.DependsOn, b.DependsOn) &&
+		a.Fingerprint != "" && a.Fingerprint == b.Fingerprint
+}
+
+func locationName(loc *time.Location) string {
+	if loc == nil {
+		return time.UTC.String()
+	}
+
+	return loc.String()
+}
+
+func sameRetryPolicy(a, b *RetryPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.MaxAttempts == b.MaxAttempts &&
+		a.InitialBackoff == b.InitialBackoff &&
+		a.MaxBackoff == b.MaxBackoff &&
+		a.Multiplier == b.Multiplier &&
+		a.Jitter == b.Jitter
+}
+
+// WatchFile reconciles the scheduler with the jobs parse reads from the file
+// at path, and then checks the file every interval and reconciles again when
+// its modification time or size changed, until ctx is done. interval must be
+// positive. For example, to run the commands of a crontab:
+//
+//	path := "/etc/myapp/crontab"
+//	err := s.WatchFile(ctx, path, time.Minute, func(r io.Reader) ([]cronroutine.JobConfig, error) {
+//		return cronroutine.ParseCrontab(r, path)
+//	})
+//
+// WatchFile returns after the first reconcile, with its error if it failed,
+// in which case the file is not watched. Errors after that are logged and
+// leave the jobs as they were until the file changes again.
+func (s *Scheduler) WatchFile(ctx context.Context, path string, interval time.Duration, parse func(r io.Reader) ([]JobConfig, error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive, got %s", interval)
+	}
+
+	info, err := s.reconcileFile(path, parse)
+	if err != nil {
+		return err
+	}
+
+	logger := s.logger.WithValues("path", path)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := os.Stat(path)
+			if err != nil {
+				logger.Error(err, "failed to check watched file")
+				continue
+			}
+			if current.ModTime().Equal(info.ModTime()) && current.Size() == info.Size() {
+				continue
+			}
+
+			// Remember the change even if it can not be applied, so
+			// that it is only reported once.
+			info = current
+			if _, err := s.reconcileFile(path, parse); err != nil {
+				logger.Error(err, "failed to reconcile jobs with watched file")
+				continue
+			}
+			logger.Info("reconciled jobs with watched file")
+		}
+	}()
+
+	return nil
+}
+
+// reconcileFile reconciles the scheduler with the jobs in the file at path, and
+// returns the file's info from before it was read.
+func (s *Scheduler) reconcileFile(path string, parse func(r io.Reader) ([]JobConfig, error)) (os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := parse(bytes.NewReader(data))
+	if err != nil {
+		return info, err
+	}
+
+	if _, err := s.Reconcile(jobs); err != nil {
+		return info, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,171 @@
+package cronroutine
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func jobIDs(s *Scheduler) []string {
+	var ids []string
+	for _, job := range s.ListJobs() {
+		ids = append(ids, job.ID())
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+func TestScheduler_Reconcile(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	fn := func(ctx context.Context) error { return nil }
+	config := func(id, schedule string) JobConfig {
+		return JobConfig{ID: id, Schedule: schedule, Retry: &RetryPolicy{MaxAttempts: 2}, Func: fn, Fingerprint: "noop"}
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		assert.NoError(t, scheduler.AddJob(config(id, "0 0 1 1 *")))
+		assert.NoError(t, scheduler.TriggerJob(context.Background(), id))
+	}
+	assert.NoError(t, scheduler.PauseJob("b"))
+
+	result, err := scheduler.Reconcile([]JobConfig{
+		config("a", "0 0 1 1 *"),
+		config("b", "0 0 2 1 *"),
+		config("d", "0 0 1 1 *"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &ReconcileResult{
+		Added:     []string{"d"},
+		Updated:   []string{"b"},
+		Removed:   []string{"c"},
+		Unchanged: []string{"a"},
+	}, result)
+	assert.Equal(t, []string{"a", "b", "d"}, jobIDs(scheduler))
+
+	b, err := scheduler.GetJob("b")
+	assert.NoError(t, err)
+	assert.Equal(t, "0 0 2 1 *", b.Schedule())
+	assert.True(t, b.Paused(), "updated jobs keep their state")
+	assert.Len(t, b.History(), 1, "updated jobs keep their history")
+
+	result, err = scheduler.Reconcile([]JobConfig{
+		config("a", "0 0 1 1 *"),
+		config("e", "not a schedule"),
+	})
+	assert.ErrorContains(t, err, "invalid job e")
+	assert.Nil(t, result)
+	assert.Equal(t, []string{"a", "b", "d"}, jobIDs(scheduler), "nothing changes when a job is invalid")
+
+	_, err = scheduler.Reconcile([]JobConfig{config("a", "0 0 1 1 *"), config("a", "0 0 1 1 *")})
+	assert.EqualError(t, err, "job with ID a is given more than once")
+
+	result, err = scheduler.Reconcile(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d"}, result.Removed)
+	assert.Empty(t, scheduler.ListJobs())
+}
+
+func Test_sameJobConfig(t *testing.T) {
+	fn := func(ctx context.Context) error { return nil }
+	other := func(ctx context.Context) error { return nil }
+	base := JobConfig{ID: "a", Schedule: "* * * * *", Func: fn, Fingerprint: "v1"}
+
+	tests := []struct {
+		name     string
+		change   func(c *JobConfig)
+		expected bool
+	}{
+		{name: "same", change: func(c *JobConfig) {}, expected: true},
+		{name: "UTC is the default", change: func(c *JobConfig) { c.Location = time.UTC }, expected: true},
+		{name: "retry policy", change: func(c *JobConfig) { c.Retry = &RetryPolicy{} }, expected: false},
+		{name: "schedule", change: func(c *JobConfig) { c.Schedule = "0 * * * *" }, expected: false},
+		{name: "fingerprint", change: func(c *JobConfig) { c.Func, c.Fingerprint = other, "v2" }, expected: false},
+		{name: "function with the same fingerprint", change: func(c *JobConfig) { c.Func = other }, expected: true},
+		{name: "deprecated concurrency", change: func(c *JobConfig) { c.ConcurrencyPolicy = ConcurrencyForbid }, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+			assert.Equal(t, tt.expected, sameJobConfig(&base, &changed))
+		})
+	}
+
+	// Without fingerprints, functions can not be compared, so even the same
+	// configuration is not the same.
+	base.Fingerprint = ""
+	assert.False(t, sameJobConfig(&base, &base))
+}
+
+func TestScheduler_ReconcileCrontab(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	reconcile := func(crontab string) *ReconcileResult {
+		jobs, err := ParseCrontab(strings.NewReader(crontab), "crontab")
+		assert.NoError(t, err)
+		result, err := scheduler.Reconcile(jobs)
+		assert.NoError(t, err)
+		return result
+	}
+
+	result := reconcile("TARGET=/tmp/old\n0 0 1 1 * echo $TARGET\n")
+	assert.Len(t, result.Added, 1)
+	id := result.Added[0]
+
+	// The job runs a closure from CommandJob either way, but its variable
+	// changed.
+	assert.Equal(t, &ReconcileResult{Updated: []string{id}}, reconcile("TARGET=/tmp/new\n0 0 1 1 * echo $TARGET\n"))
+	assert.Equal(t, &ReconcileResult{Unchanged: []string{id}}, reconcile("TARGET=/tmp/new\n0 0 1 1 * echo $TARGET\n"))
+
+	assert.NoError(t, scheduler.TriggerJob(context.Background(), id))
+	job, err := scheduler.GetJob(id)
+	assert.NoError(t, err)
+	if history := job.History(); assert.Len(t, history, 1) {
+		assert.Equal(t, "/tmp/new\n", history[0].Result())
+	}
+}
+
+func TestScheduler_WatchFile(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	handlers := Handlers{"noop": func(ctx context.Context) error { return nil }}
+	path := filepath.Join(t.TempDir(), "jobs.yaml")
+	write := func(ids ...string) {
+		var doc strings.Builder
+		doc.WriteString("jobs:\n")
+		for _, id := range ids {
+			doc.WriteString("  - {id: " + id + ", schedule: \"0 0 * * *\", handler: noop}\n")
+		}
+		assert.NoError(t, os.WriteFile(path, []byte(doc.String()), 0o644))
+	}
+	parse := func(r io.Reader) ([]JobConfig, error) { return ParseJobConfigs(r, handlers) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.Error(t, scheduler.WatchFile(ctx, path, time.Millisecond, parse), "the file does not exist")
+	assert.EqualError(t, scheduler.WatchFile(ctx, path, 0, parse), "watch interval must be positive, got 0s")
+
+	write("a", "b")
+	assert.NoError(t, scheduler.WatchFile(ctx, path, time.Millisecond, parse))
+	assert.Equal(t, []string{"a", "b"}, jobIDs(scheduler))
+
+	write("b", "c", "d")
+	assert.Eventually(t, func() bool {
+		return slices.Equal([]string{"b", "c", "d"}, jobIDs(scheduler))
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(path, []byte("jobs: [{id: e, handler: missing}]"), 0o644))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{"b", "c", "d"}, jobIDs(scheduler), "an invalid file leaves the jobs alone")
+}
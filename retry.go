@@ -0,0 +1,67 @@
+package cronroutine
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy determines whether and when a failed run is attempted again.
+// Every attempt gets its own entry in the job's history.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a run is attempted,
+	// including the first attempt. Values less than 1 are treated as 1.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the time to wait between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff grows by after every retry.
+	// Values less than 1 are treated as 2.
+	Multiplier float64
+
+	// Jitter is the fraction of the backoff, between 0 and 1, that is
+	// randomly subtracted from it so that retries of many jobs spread out.
+	Jitter float64
+
+	// Retryable reports whether a run that failed with err should be
+	// retried. If it is nil, every error is retried.
+	Retryable func(err error) bool
+}
+
+// shouldRetry reports whether another attempt should be made after the given
+// attempt failed with err.
+func (r *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if r == nil || attempt >= r.MaxAttempts {
+		return false
+	}
+
+	return r.Retryable == nil || r.Retryable(err)
+}
+
+// backoff returns the time to wait after the given attempt failed.
+func (r *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := r.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	backoff := float64(r.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if r.MaxBackoff > 0 {
+		backoff = math.Min(backoff, float64(r.MaxBackoff))
+	}
+
+	jitter := math.Max(0, math.Min(r.Jitter, 1))
+	backoff -= backoff * jitter * rand.Float64()
+
+	// Without a cap the backoff can grow past the longest Duration, and
+	// converting it would overflow.
+	if backoff >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return time.Duration(backoff)
+}
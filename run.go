@@ -0,0 +1,164 @@
+package cronroutine
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Trigger describes what caused a job to run.
+type Trigger string
+
+const (
+	// TriggerSchedule is a run started because the job's schedule fired.
+	TriggerSchedule Trigger = "Schedule"
+
+	// TriggerManual is a run started with TriggerJob or TriggerJobAsync.
+	TriggerManual Trigger = "Manual"
+
+	// TriggerCatchUp is a run started for a missed fire time according to
+	// the job's MisfirePolicy.
+	TriggerCatchUp Trigger = "CatchUp"
+
+	// TriggerDependency is a run started because the jobs it depends on
+	// finished.
+	TriggerDependency Trigger = "Dependency"
+)
+
+// scheduled reports whether the run is for a fire time of the job's schedule,
+// rather than started on demand.
+func (t Trigger) scheduled() bool {
+	return t == TriggerSchedule || t == TriggerCatchUp
+}
+
+// RunInfo describes an attempt of a job run.
+type RunInfo struct {
+	JobID string
+
+	// RunID identifies the run. All attempts of a run share its ID.
+	RunID   string
+	Trigger Trigger
+
+	// ScheduledTime is the fire time the run is for. For manual runs it is
+	// the time the run was triggered.
+	ScheduledTime time.Time
+
+	// Attempt is which attempt of the run this is, starting at 1.
+	Attempt int
+
+	// UpstreamRunIDs are the runs of the jobs this job depends on that
+	// started it, if Trigger is TriggerDependency.
+	UpstreamRunIDs []string
+}
+
+// maxResultSize is the maximum number of bytes of a run's result that are kept
+// in its History entry.
+const maxResultSize = 4096
+
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func (j *jobMetadata) newRun(trigger Trigger, scheduledTime time.Time, startTime time.Time) *scheduledJob {
+	return &scheduledJob{
+		job:           j,
+		id:            newRunID(),
+		trigger:       trigger,
+		scheduledTime: scheduledTime,
+		startTime:     startTime,
+	}
+}
+
+// newManualRun returns a run of the job that is triggered now.
+func (j *jobMetadata) newManualRun() *scheduledJob {
+	now := time.Now().UTC()
+	return j.newRun(TriggerManual, now, now)
+}
+
+// history returns a History entry for an attempt of the run. The caller fills
+// in the outcome.
+func (r *scheduledJob) history(attempt int, startedAt time.Time) *History {
+	return &History{
+		jobID:          r.job.ID(),
+		runID:          r.id,
+		trigger:        r.trigger,
+		attempt:        attempt,
+		scheduledAt:    r.scheduledTime,
+		startedAt:      startedAt,
+		upstreamRunIDs: r.upstream,
+	}
+}
+
+// runInfo describes the run before any attempt has started.
+func (r *scheduledJob) runInfo() *RunInfo {
+	return &RunInfo{
+		JobID:          r.job.ID(),
+		RunID:          r.id,
+		Trigger:        r.trigger,
+		ScheduledTime:  r.scheduledTime,
+		UpstreamRunIDs: slices.Clone(r.upstream),
+	}
+}
+
+type resultKey struct{}
+
+type runResult struct {
+	lock   sync.Mutex
+	result string
+}
+
+func (r *runResult) get() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.result
+}
+
+// SetResult records a small result for the job run that ctx belongs to. The
+// result is kept in the run's History entry, truncated to 4 KiB. It does
+// nothing if ctx was not passed to a job by the Scheduler.
+func SetResult(ctx context.Context, result string) {
+	r, ok := ctx.Value(resultKey{}).(*runResult)
+	if !ok {
+		return
+	}
+
+	if len(result) > maxResultSize {
+		result = result[:maxResultSize]
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.result = result
+}
+
+type runInfoKey struct{}
+
+// RunInfoFromContext returns the attempt of the job run that ctx belongs to. It
+// returns false if ctx was not passed to a job by the Scheduler.
+func RunInfoFromContext(ctx context.Context) (*RunInfo, bool) {
+	info, ok := ctx.Value(runInfoKey{}).(*RunInfo)
+	if !ok {
+		return nil, false
+	}
+
+	ret := *info
+	return &ret, true
+}
+
+// LoggerFromContext returns the scheduler's logger for the job run that ctx
+// belongs to, named after the job and with the run ID and attempt as values.
+// It returns a logger that discards everything if ctx was not passed to a job
+// by the Scheduler. The same logger is available with logr.FromContext.
+func LoggerFromContext(ctx context.Context) logr.Logger {
+	return logr.FromContextOrDiscard(ctx)
+}
@@ -0,0 +1,46 @@
+package cronroutine
+
+import "context"
+
+// RunHandle tracks a job run that was started with TriggerJobAsync.
+type RunHandle struct {
+	jobID  string
+	runID  string
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+func newRunHandle(jobID, runID string, cancel context.CancelFunc) *RunHandle {
+	return &RunHandle{
+		jobID:  jobID,
+		runID:  runID,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+func (r *RunHandle) finish(err error) {
+	r.err = err
+	close(r.done)
+}
+
+func (r *RunHandle) JobID() string { return r.jobID }
+
+// RunID returns the ID of the run. The History entries of its attempts have
+// the same RunID, so once the run has finished its result can be found in the
+// job's history.
+func (r *RunHandle) RunID() string { return r.runID }
+
+// Done returns a channel that is closed when the run finishes.
+func (r *RunHandle) Done() <-chan struct{} { return r.done }
+
+// Cancel cancels the context passed to the job. It does not wait for the run
+// to finish.
+func (r *RunHandle) Cancel() { r.cancel() }
+
+// Wait blocks until the run finishes and returns its result.
+func (r *RunHandle) Wait() error {
+	<-r.done
+	return r.err
+}
@@ -1,29 +1,96 @@
 package cronroutine
 
 import (
+	"context"
 	"fmt"
 	"runtime"
+	"slices"
 	"sort"
 	"sync"
 	"time"
 
-	"github.com/cilium/workerpool"
 	"github.com/go-logr/logr"
 )
 
 type Scheduler struct {
 	jobsLock sync.RWMutex
 	jobs     map[string]*jobMetadata
-
-	logger       logr.Logger
-	historyLimit int
-	workerpool   *workerpool.WorkerPool
+	wake     chan struct{}
+	events   *eventBus
+
+	// removed holds the IDs of the jobs that have been removed. Their
+	// history does not say when they were last scheduled if they are added
+	// again.
+	removed map[string]bool
+
+	logger        logr.Logger
+	historyStore  HistoryStore
+	historyLimit  int
+	stateStore    StateStore
+	locker        Locker
+	middleware    []Middleware
+	hooks         Hooks
+	recoverPanics bool
+	metrics       Metrics
+	tracer        Tracer
+	dispatcher    *dispatcher
 }
 
 type SchedulerConfig struct {
-	Logger       logr.Logger
+	Logger logr.Logger
+
+	// HistoryLimit is the number of most recent runs returned by
+	// Job.History. It is also the number of runs per job kept by the
+	// default HistoryStore.
 	HistoryLimit int
 	WorkerCount  int
+
+	// HistoryStore stores the history of job runs. If it is nil, the most
+	// recent HistoryLimit runs of every job are kept in memory.
+	HistoryStore HistoryStore
+
+	// StateStore persists the state of jobs, such as when they last ran and
+	// whether they are paused, so that it survives restarts. If it is nil,
+	// state is only kept in memory.
+	StateStore StateStore
+
+	// Locker makes sure each fire time of a job is run by only one
+	// Scheduler when several share the same jobs. If it is nil, every
+	// Scheduler runs every fire time.
+	Locker Locker
+
+	// Middleware wraps the function of every job. The first middleware is
+	// the outermost.
+	Middleware []Middleware
+
+	// Hooks are called as the runs of every job start and finish.
+	Hooks Hooks
+
+	// DisablePanicRecovery lets a panic in a job's function crash the
+	// process instead of being recorded as ErrJobPanicked.
+	DisablePanicRecovery bool
+
+	// Metrics receives measurements of runs and of the worker pool. If it is
+	// nil, nothing is measured.
+	Metrics Metrics
+
+	// GroupLimits is the maximum number of runs of the jobs in each group
+	// that the worker pool runs at once. Groups that are not in it, or
+	// whose limit is not positive, are only limited by WorkerCount. Runs
+	// waiting for their group do not hold a worker.
+	GroupLimits map[string]int
+
+	// GroupCapacity reserves workers for groups and shares the rest between
+	// them by weight, so that a burst of runs in one group can not hold up
+	// the others. The "" key is for jobs without a group. Groups that are
+	// not in it reserve nothing and have a weight of 1. Within a group, runs
+	// of jobs with a higher Priority start first.
+	GroupCapacity map[string]GroupCapacity
+
+	// Tracer traces every attempt of a run, for example with OpenTelemetry
+	// through the otelcronroutine package. If it is nil, runs are not
+	// traced.
+	Tracer Tracer
 }
 
 func DefaultSchedulerConfig() *SchedulerConfig {
@@ -39,30 +106,51 @@ func StartNewScheduler(cfg *SchedulerConfig) *Scheduler {
 		cfg = DefaultSchedulerConfig()
 	}
 
+	historyStore := cfg.HistoryStore
+	if historyStore == nil {
+		historyStore = NewMemoryHistoryStore(cfg.HistoryLimit)
+	}
+
+	metrics := cfg.Metrics
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+
+	tracer := cfg.Tracer
+	if tracer == nil {
+		tracer = nopTracer{}
+	}
+
 	s := &Scheduler{
 		jobsLock: sync.RWMutex{},
 		jobs:     make(map[string]*jobMetadata),
-
-		workerpool:   workerpool.New(cfg.WorkerCount),
-		logger:       cfg.Logger,
-		historyLimit: cfg.HistoryLimit,
+		removed:  make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+		events:   newEventBus(),
+
+		logger:        cfg.Logger,
+		historyStore:  historyStore,
+		historyLimit:  cfg.HistoryLimit,
+		stateStore:    cfg.StateStore,
+		locker:        cfg.Locker,
+		middleware:    cfg.Middleware,
+		hooks:         cfg.Hooks,
+		recoverPanics: !cfg.DisablePanicRecovery,
+		metrics:       metrics,
+		tracer:        tracer,
 	}
+	s.dispatcher = newDispatcher(cfg.WorkerCount, cfg.GroupCapacity, cfg.GroupLimits,
+		&poolMetrics{metrics: metrics, workers: cfg.WorkerCount}, cfg.Logger)
 
+	metrics.WorkersBusy(0, cfg.WorkerCount)
 	s.start()
 	return s
 }
 
+// StopScheduler drops the runs waiting for a worker, cancels the context of
+// the runs that have started and waits for them to finish.
 func StopScheduler(s *Scheduler) error {
-	if err := s.workerpool.Close(); err != nil {
-		return fmt.Errorf("failed to close worker pool: %w", err)
-	}
-
-	// The workerpool keeps a history of all the tasks. We don't need to keep
-	// the history, so this library may not be suitable.
-	if _, err := s.workerpool.Drain(); err != nil {
-		return fmt.Errorf("failed to drain worker pool: %w", err)
-	}
-
+	s.dispatcher.stop()
 	return nil
 }
 
@@ -74,41 +162,164 @@ func (s *Scheduler) AddJob(job JobConfig) error {
 		return fmt.Errorf("job with ID %s already exists", job.ID)
 	}
 
-	cron, err := ParseCron(job.Schedule)
+	if err := s.checkDependencies(job); err != nil {
+		return err
+	}
+
+	metadata, err := s.newJob(job)
+	if err != nil {
+		return err
+	}
+
+	s.jobs[job.ID] = metadata
+	s.events.publish(Event{Type: EventJobAdded, JobID: job.ID})
+	s.wakeQueueLoop()
+
+	return nil
+}
+
+// newJob returns the metadata of a job that is being added, with the state it
+// had the last time it was saved.
+func (s *Scheduler) newJob(job JobConfig) (*jobMetadata, error) {
+	var state JobState
+	if s.stateStore != nil {
+		saved, err := s.stateStore.Load(job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load job state: %w", err)
+		}
+		if saved != nil {
+			state = *saved
+		}
+	}
+
+	metadata, err := s.newJobMetadata(job, newJobRuntime(state))
 	if err != nil {
-		return fmt.Errorf("failed to parse cron schedule: %w", err)
+		return nil, err
+	}
+
+	// Fire times that passed while the job was removed are not missed.
+	if s.removed[job.ID] && state.LastScheduled.IsZero() {
+		metadata.lastScheduled = time.Now().UTC()
+	}
+
+	return metadata, nil
+}
+
+// UpdateJob replaces the configuration of an existing job. The job keeps its
+// history and state, and runs that are in progress are not interrupted but
+// still count towards the job's concurrency policy. Runs that were scheduled
+// under the old configuration and have not started yet are dropped.
+func (s *Scheduler) UpdateJob(job JobConfig) error {
+	s.jobsLock.Lock()
+	defer s.jobsLock.Unlock()
+
+	existing, ok := s.jobs[job.ID]
+	if !ok {
+		return ErrJobNotFound{ID: job.ID}
 	}
 
-	s.jobs[job.ID] = &jobMetadata{
-		jobConfig:    &job,
-		history:      make([]*History, 0, s.historyLimit),
-		historyLimit: s.historyLimit,
-		cron:         cron,
-		running:      sync.Mutex{},
+	if err := s.checkDependencies(job); err != nil {
+		return err
 	}
 
+	metadata, err := s.updatedJob(existing, job)
+	if err != nil {
+		return err
+	}
+
+	s.jobs[job.ID] = metadata
+	s.events.publish(Event{Type: EventJobUpdated, JobID: job.ID})
+	s.wakeQueueLoop()
+
 	return nil
 }
 
-func (s *Scheduler) ListJobs() []*Job {
+// updatedJob returns the metadata that replaces existing when its
+// configuration changes to job.
+func (s *Scheduler) updatedJob(existing *jobMetadata, job JobConfig) (*jobMetadata, error) {
+	metadata, err := s.newJobMetadata(job, existing.jobRuntime)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fire times that passed under the old configuration are not missed.
+	metadata.lastScheduled = time.Now().UTC()
+
+	return metadata, nil
+}
+
+func (s *Scheduler) newJobMetadata(job JobConfig, runtime *jobRuntime) (*jobMetadata, error) {
+	if err := job.ConcurrencyPolicy.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := job.MisfirePolicy.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validateDependencies(job.DependsOn); err != nil {
+		return nil, err
+	}
+
+	// A job that only runs after the jobs it depends on has a schedule
+	// that never fires.
+	cron := &Cron{}
+	if job.Schedule != "" || len(job.DependsOn) == 0 {
+		var err error
+		cron, err = ParseCron(job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cron schedule: %w", err)
+		}
+	}
+	cron.Location = job.Location
+
+	return &jobMetadata{
+		jobConfig:     &job,
+		fn:            chain(job.Func, append(slices.Clone(s.middleware), job.Middleware...)...),
+		hooks:         []Hooks{s.hooks, job.Hooks},
+		logger:        s.logger.WithName(job.ID),
+		historyStore:  s.historyStore,
+		historyLimit:  s.historyLimit,
+		cron:          cron,
+		recoverPanics: s.recoverPanics,
+		stateStore:    s.stateStore,
+		locker:        s.locker,
+		events:        s.events,
+		metrics:       s.metrics,
+		tracer:        s.tracer,
+		upstreamDone:  s.upstreamFinished,
+		current:       s.isCurrent,
+		jobRuntime:    runtime,
+	}, nil
+}
+
+// Subscribe returns a Subscription that receives everything the scheduler does
+// from now on. Up to bufferSize events are buffered for the subscriber before
+// new events are dropped.
+func (s *Scheduler) Subscribe(bufferSize int) *Subscription {
+	return s.events.subscribe(bufferSize)
+}
+
+// ListJobs returns the jobs that match all of filters.
+func (s *Scheduler) ListJobs(filters ...JobFilter) []*Job {
 	s.jobsLock.RLock()
 	defer s.jobsLock.RUnlock()
 
 	jobs := make([]*Job, 0, len(s.jobs))
-	for _, job := range s.jobs {
-		jobs = append(jobs, job.Job())
+	for _, metadata := range s.jobs {
+		job := metadata.Job()
+		if !slices.ContainsFunc(filters, func(f JobFilter) bool { return !f(job) }) {
+			jobs = append(jobs, job)
+		}
 	}
 
 	return jobs
 }
 
 func (s *Scheduler) GetJob(jobID string) (*Job, error) {
-	s.jobsLock.RLock()
-	defer s.jobsLock.RUnlock()
-
-	job, ok := s.jobs[jobID]
-	if !ok {
-		return nil, fmt.Errorf("job with ID %s does not exist", jobID)
+	job, err := s.getJobMetadata(jobID)
+	if err != nil {
+		return nil, err
 	}
 
 	return job.Job(), nil
@@ -119,31 +330,205 @@ func (s *Scheduler) RemoveJob(jobID string) error {
 	defer s.jobsLock.Unlock()
 
 	if _, existed := s.jobs[jobID]; !existed {
-		return fmt.Errorf("job with ID %s does not exist", jobID)
+		return ErrJobNotFound{ID: jobID}
 	}
 
+	return s.removeJob(jobID)
+}
+
+// removeJob removes a job that exists. The caller must hold jobsLock.
+func (s *Scheduler) removeJob(jobID string) error {
 	delete(s.jobs, jobID)
+	s.removed[jobID] = true
+	s.events.publish(Event{Type: EventJobRemoved, JobID: jobID})
+
+	if s.stateStore != nil {
+		if err := s.stateStore.Delete(jobID); err != nil {
+			return fmt.Errorf("failed to delete job state: %w", err)
+		}
+	}
 
 	return nil
 }
 
-func (s *Scheduler) start() {
-	type scheduledJob struct {
-		job       *jobMetadata
-		startTime time.Time
+// History returns the runs in the scheduler's HistoryStore that match q,
+// including runs of jobs that have since been removed.
+func (s *Scheduler) History(q HistoryQuery) ([]*History, error) {
+	history, err := s.historyStore.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+
+	return history, nil
+}
+
+// PauseJob stops the job from being scheduled until ResumeJob is called. Runs
+// that have already started are not affected, and the job can still be
+// triggered manually.
+func (s *Scheduler) PauseJob(jobID string) error {
+	job, err := s.getJobMetadata(jobID)
+	if err != nil {
+		return err
+	}
+
+	job.setPaused(true)
+	return nil
+}
+
+// ResumeJob resumes scheduling of a paused job. Fire times that were missed
+// while the job was paused are handled according to its MisfirePolicy.
+func (s *Scheduler) ResumeJob(jobID string) error {
+	job, err := s.getJobMetadata(jobID)
+	if err != nil {
+		return err
+	}
+
+	if job.setPaused(false) {
+		s.wakeQueueLoop()
+	}
+	return nil
+}
+
+// TriggerJob runs the job outside of its schedule as soon as a worker is free,
+// and waits for the run to finish. The run is subject to the same timeout,
+// concurrency and history rules as a scheduled run. If ctx is done before the
+// run starts, it does not start.
+func (s *Scheduler) TriggerJob(ctx context.Context, jobID string) error {
+	job, err := s.getJobMetadata(jobID)
+	if err != nil {
+		return err
+	}
+
+	return s.runManually(ctx, job.newManualRun())
+}
+
+// TriggerJobAsync runs the job like TriggerJob does without waiting, and
+// returns a handle that can be used to wait for or cancel the run, and to find
+// its History entries by their run ID.
+func (s *Scheduler) TriggerJobAsync(ctx context.Context, jobID string) (*RunHandle, error) {
+	job, err := s.getJobMetadata(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := job.newManualRun()
+	ctx, cancel := context.WithCancel(ctx)
+	handle := newRunHandle(jobID, r.id, cancel)
+	go func() {
+		defer cancel()
+		handle.finish(s.runManually(ctx, r))
+	}()
+
+	return handle, nil
+}
+
+// runManually runs r, a run started outside of its job's schedule, on the
+// worker pool and waits for it to finish.
+func (s *Scheduler) runManually(ctx context.Context, r *scheduledJob) error {
+	if err := r.job.awaitTurn(ctx, r); err != nil {
+		return r.job.couldNotStart(r, err)
+	}
+	// The turn is only still reserved if the run did not start.
+	defer r.job.releaseTurn(r)
+
+	config := r.job.jobConfig
+	return s.dispatcher.run(ctx, r.job.ID(), config.Group, config.Priority, r.job.run(r))
+}
+
+// isCurrent reports whether j is the metadata of one of the scheduler's jobs,
+// rather than of a job that has since been removed or updated.
+func (s *Scheduler) isCurrent(j *jobMetadata) bool {
+	current, err := s.getJobMetadata(j.ID())
+	return err == nil && current == j
+}
+
+func (s *Scheduler) getJobMetadata(jobID string) (*jobMetadata, error) {
+	s.jobsLock.RLock()
+	defer s.jobsLock.RUnlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound{ID: jobID}
+	}
+
+	return job, nil
+}
+
+// wakeQueueLoop makes the queue loop schedule jobs again without waiting for
+// the last scheduled job.
+func (s *Scheduler) wakeQueueLoop() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+type scheduledJob struct {
+	job           *jobMetadata
+	id            string
+	trigger       Trigger
+	scheduledTime time.Time
+
+	// upstream are the IDs of the upstream runs that started a run
+	// triggered by its dependencies.
+	upstream []string
+
+	// startTime is when the run should start and the time its starting
+	// deadline counts from. It differs from scheduledTime for catch-up runs.
+	startTime time.Time
+
+	// turn is whether a slot of a job with the Queue concurrency policy is
+	// reserved for the run. It is guarded by the job's runLock.
+	turn bool
+}
+
+// submit queues r to run when a worker is free for it. It does not wait for
+// the run to start. Runs of jobs with the Queue concurrency policy first wait
+// for their turn without a worker.
+func (s *Scheduler) submit(r *scheduledJob) {
+	if r.job.jobConfig.concurrencyPolicy() != ConcurrencyQueue {
+		s.dispatch(r)
+		return
 	}
 
+	go func() {
+		if err := r.job.awaitTurn(s.dispatcher.ctx, r); err != nil {
+			r.job.couldNotStart(r, err)
+			return
+		}
+		s.dispatch(r)
+	}()
+}
+
+func (s *Scheduler) dispatch(r *scheduledJob) {
+	config := r.job.jobConfig
+	err := s.dispatcher.submit(r.job.ID(), config.Group, config.Priority, r.job.run(r))
+	if err != nil {
+		s.logger.Error(err, "failed to submit job to worker pool", "job_id", r.job.ID())
+		r.job.releaseTurn(r)
+	}
+}
+
+func (s *Scheduler) start() {
 	workQueue := make(chan *scheduledJob, 100)
 	go func() {
 		wLog := s.logger.WithName("worker-loop")
 		wLog.Info("worker loop started")
 		for job := range workQueue {
-			time.Sleep(time.Until(job.startTime))
-			wLog.Info("job started", "job_id", job.job.ID(), "time", job.startTime)
-			err := s.workerpool.Submit(job.job.ID(), job.job.run(s.logger.WithName(job.job.ID()), job.startTime))
-			if err != nil {
-				wLog.Error(err, "failed to submit job to worker pool", "job_id", job.job.ID())
-			}
+			job := job
+			time.AfterFunc(time.Until(job.startTime), func() {
+				if !s.isCurrent(job.job) {
+					wLog.Info("job removed, skipping run", "job_id", job.job.ID(), "time", job.startTime)
+					return
+				}
+				if job.job.dropIfPaused(job) {
+					wLog.Info("job paused, skipping run", "job_id", job.job.ID(), "time", job.startTime)
+					return
+				}
+
+				wLog.Info("job started", "job_id", job.job.ID(), "time", job.startTime)
+				s.submit(job)
+			})
 		}
 	}()
 
@@ -153,15 +538,14 @@ func (s *Scheduler) start() {
 		for {
 			scheduledJobs := make([]*scheduledJob, 0, 100)
 
+			now := time.Now().UTC()
+			horizon := now.Add(3 * time.Minute)
 			s.jobsLock.RLock()
 			for _, job := range s.jobs {
-				schedule := job.cron.NextFor(3 * time.Minute)
-				for _, t := range schedule {
-					scheduledJobs = append(scheduledJobs, &scheduledJob{
-						job:       job,
-						startTime: t,
-					})
+				if job.isPaused() {
+					continue
 				}
+				scheduledJobs = append(scheduledJobs, job.nextRuns(qLog, now, horizon)...)
 			}
 			s.jobsLock.RUnlock()
 
@@ -169,18 +553,20 @@ func (s *Scheduler) start() {
 				return scheduledJobs[i].startTime.Before(scheduledJobs[j].startTime)
 			})
 
-			lastJobTime := time.Now().UTC()
-			if len(scheduledJobs) > 0 {
-				lastJobTime = scheduledJobs[len(scheduledJobs)-1].startTime
-			}
-
 			for _, job := range scheduledJobs {
 				qLog.Info("job scheduled", "job_id", job.job.ID(), "time", job.startTime)
+				s.events.publish(Event{Type: EventRunScheduled, JobID: job.job.ID(), Run: job.runInfo()})
 				workQueue <- job
 			}
 
-			qLog.Info("sleeping for until last job", "time", lastJobTime)
-			time.Sleep(time.Until(lastJobTime.Add(10 * time.Millisecond)))
+			// Every run up to the horizon has been handed to the worker loop,
+			// so there is nothing to do until then unless a job is added or
+			// resumed.
+			qLog.Info("sleeping until horizon", "time", horizon)
+			select {
+			case <-time.After(time.Until(horizon)):
+			case <-s.wake:
+			}
 		}
 	}()
 }
@@ -2,12 +2,19 @@ package cronroutine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	"github.com/go-logr/zapr"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -131,3 +138,840 @@ func TestScheduler_dontStartConcurrentRuns(t *testing.T) {
 	expectedErr := ErrJobRunning{}
 	assert.EqualError(t, history[1].Error(), expectedErr.Error())
 }
+
+func TestScheduler_TriggerJob(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	testID := "test-0"
+	var jobsRun atomic.Uint64
+
+	err := scheduler.AddJob(JobConfig{
+		ID:                testID,
+		Schedule:          "0 0 1 1 *",
+		Timeout:           time.Second,
+		StartingDeadline:  time.Second,
+		ConcurrencyPolicy: ConcurrencyAllow,
+		Func: func(ctx context.Context) error {
+			jobsRun.Add(1)
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	err = scheduler.TriggerJob(context.Background(), testID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, int(jobsRun.Load()))
+
+	handle, err := scheduler.TriggerJobAsync(context.Background(), testID)
+	assert.NoError(t, err)
+	assert.Equal(t, testID, handle.JobID())
+	assert.NoError(t, handle.Wait())
+	assert.Equal(t, 2, int(jobsRun.Load()))
+
+	jobOne, err := scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	history := jobOne.History()
+	assert.Equal(t, 2, len(history))
+	assert.NotEmpty(t, handle.RunID())
+	assert.Equal(t, handle.RunID(), history[1].RunID(), "the handle finds the run's history")
+	assert.NotEqual(t, history[0].RunID(), history[1].RunID())
+
+	err = scheduler.TriggerJob(context.Background(), "does-not-exist")
+	assert.EqualError(t, err, "job with ID does-not-exist does not exist")
+}
+
+func TestScheduler_concurrencyPolicies(t *testing.T) {
+	t.Parallel()
+
+	newBlockingJob := func(id string, policy ConcurrencyPolicy, started chan<- struct{}, unblock <-chan struct{}) JobConfig {
+		return JobConfig{
+			ID:                id,
+			Schedule:          "0 0 1 1 *",
+			Timeout:           10 * time.Second,
+			StartingDeadline:  10 * time.Second,
+			ConcurrencyPolicy: policy,
+			QueueDepth:        1,
+			Func: func(ctx context.Context) error {
+				started <- struct{}{}
+				select {
+				case <-ctx.Done():
+				case <-unblock:
+				}
+				return nil
+			},
+		}
+	}
+
+	// Manual runs wait for a free worker like scheduled ones, so each
+	// scheduler has a worker for every run the tests start at once.
+	t.Run("forbid skips the new run", func(t *testing.T) {
+		scheduler := newTestScheduler(2)
+		started, unblock := make(chan struct{}, 2), make(chan struct{})
+		assert.NoError(t, scheduler.AddJob(newBlockingJob("forbid", ConcurrencyForbid, started, unblock)))
+
+		first, err := scheduler.TriggerJobAsync(context.Background(), "forbid")
+		assert.NoError(t, err)
+		<-started
+
+		err = scheduler.TriggerJob(context.Background(), "forbid")
+		assert.ErrorIs(t, err, ErrJobRunning{})
+
+		close(unblock)
+		assert.NoError(t, first.Wait())
+	})
+
+	t.Run("replace cancels the active run", func(t *testing.T) {
+		scheduler := newTestScheduler(2)
+		started, unblock := make(chan struct{}, 2), make(chan struct{})
+		assert.NoError(t, scheduler.AddJob(newBlockingJob("replace", ConcurrencyReplace, started, unblock)))
+
+		first, err := scheduler.TriggerJobAsync(context.Background(), "replace")
+		assert.NoError(t, err)
+		<-started
+
+		second, err := scheduler.TriggerJobAsync(context.Background(), "replace")
+		assert.NoError(t, err)
+		<-started
+		assert.ErrorIs(t, first.Wait(), ErrJobReplaced{})
+
+		close(unblock)
+		assert.NoError(t, second.Wait())
+	})
+
+	t.Run("queue waits for the active run", func(t *testing.T) {
+		// Queued runs wait without a worker, so the second worker is free
+		// for other jobs.
+		scheduler := newTestScheduler(2)
+		started, unblock := make(chan struct{}, 2), make(chan struct{})
+		assert.NoError(t, scheduler.AddJob(newBlockingJob("queue", ConcurrencyQueue, started, unblock)))
+		assert.NoError(t, scheduler.AddJob(JobConfig{ID: "other", Schedule: "0 0 1 1 *", Func: func(ctx context.Context) error { return nil }}))
+
+		first, err := scheduler.TriggerJobAsync(context.Background(), "queue")
+		assert.NoError(t, err)
+		<-started
+
+		second, err := scheduler.TriggerJobAsync(context.Background(), "queue")
+		assert.NoError(t, err)
+		job, err := scheduler.getJobMetadata("queue")
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			job.runLock.Lock()
+			defer job.runLock.Unlock()
+			return len(job.waiting) == 1
+		}, time.Second, 10*time.Millisecond)
+		assert.ErrorIs(t, scheduler.TriggerJob(context.Background(), "queue"), ErrQueueFull{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NoError(t, scheduler.TriggerJob(ctx, "other"), "queued runs do not hold a worker")
+
+		select {
+		case <-started:
+			t.Fatal("queued run started while another run was active")
+		default:
+		}
+
+		close(unblock)
+		assert.NoError(t, first.Wait())
+		assert.NoError(t, second.Wait())
+		assert.Len(t, started, 1)
+	})
+
+	t.Run("unknown policies are rejected", func(t *testing.T) {
+		scheduler := newTestScheduler(1)
+		fn := func(ctx context.Context) error { return nil }
+
+		err := scheduler.AddJob(JobConfig{ID: "typo", Schedule: "* * * * *", ConcurrencyPolicy: "forbid", Func: fn})
+		assert.EqualError(t, err, `unknown concurrency policy "forbid"`)
+		err = scheduler.AddJob(JobConfig{ID: "typo", Schedule: "* * * * *", MisfirePolicy: "runAll", Func: fn})
+		assert.EqualError(t, err, `unknown misfire policy "runAll"`)
+
+		assert.NoError(t, scheduler.AddJob(JobConfig{ID: "allow", Schedule: "* * * * *", ConcurrencyPolicy: ConcurrencyAllow, Func: fn}))
+		job, err := scheduler.GetJob("allow")
+		assert.NoError(t, err)
+		assert.True(t, job.AllowConccurentRuns())
+	})
+}
+
+func TestScheduler_PauseJob(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	testID := "test-0"
+
+	err := scheduler.AddJob(JobConfig{
+		ID:       testID,
+		Schedule: "* * * * *",
+		Func:     func(ctx context.Context) error { return nil },
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, scheduler.PauseJob(testID))
+	jobOne, err := scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	assert.True(t, jobOne.Paused())
+
+	assert.NoError(t, scheduler.ResumeJob(testID))
+	jobOne, err = scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	assert.False(t, jobOne.Paused())
+
+	assert.EqualError(t, scheduler.PauseJob("does-not-exist"), "job with ID does-not-exist does not exist")
+}
+
+func TestScheduler_retryFailedRuns(t *testing.T) {
+	t.Parallel()
+	errFlaky := errors.New("flaky upstream")
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name             string
+		failures         int
+		err              error
+		expectedAttempts int
+		expectedErr      error
+	}{
+		{
+			name:             "succeeds after retries",
+			failures:         2,
+			err:              errFlaky,
+			expectedAttempts: 3,
+			expectedErr:      nil,
+		},
+		{
+			name:             "gives up after max attempts",
+			failures:         5,
+			err:              errFlaky,
+			expectedAttempts: 3,
+			expectedErr:      errFlaky,
+		},
+		{
+			name:             "does not retry errors that are not retryable",
+			failures:         5,
+			err:              errFatal,
+			expectedAttempts: 1,
+			expectedErr:      errFatal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newTestScheduler(1)
+			var calls atomic.Int64
+
+			err := scheduler.AddJob(JobConfig{
+				ID:                "retry",
+				Schedule:          "0 0 1 1 *",
+				Timeout:           time.Second,
+				ConcurrencyPolicy: ConcurrencyForbid,
+				Retry: &RetryPolicy{
+					MaxAttempts:    3,
+					InitialBackoff: 10 * time.Millisecond,
+					Jitter:         0.5,
+					Retryable:      func(err error) bool { return !errors.Is(err, errFatal) },
+				},
+				Func: func(ctx context.Context) error {
+					if calls.Add(1) <= int64(tt.failures) {
+						return tt.err
+					}
+					return nil
+				},
+			})
+			assert.NoError(t, err)
+
+			err = scheduler.TriggerJob(context.Background(), "retry")
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			job, err := scheduler.GetJob("retry")
+			assert.NoError(t, err)
+			history := job.History()
+			assert.Len(t, history, tt.expectedAttempts)
+			for i, h := range history {
+				assert.Equal(t, i+1, h.Attempt())
+			}
+			assert.Equal(t, tt.expectedErr, history[len(history)-1].Error())
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+	}
+
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 2*time.Second, policy.backoff(2))
+	assert.Equal(t, 4*time.Second, policy.backoff(3))
+	assert.Equal(t, 5*time.Second, policy.backoff(4))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := policy.backoff(2)
+		assert.GreaterOrEqual(t, backoff, time.Second)
+		assert.LessOrEqual(t, backoff, 2*time.Second)
+	}
+
+	uncapped := &RetryPolicy{MaxAttempts: 100, InitialBackoff: time.Second}
+	assert.Equal(t, time.Duration(math.MaxInt64), uncapped.backoff(100))
+}
+
+func TestScheduler_recoverPanics(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	testID := "test-0"
+
+	err := scheduler.AddJob(JobConfig{
+		ID:                testID,
+		Schedule:          "0 0 1 1 *",
+		Timeout:           time.Second,
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Func: func(ctx context.Context) error {
+			panic("something went wrong")
+		},
+	})
+	assert.NoError(t, err)
+
+	err = scheduler.TriggerJob(context.Background(), testID)
+	var panicked ErrJobPanicked
+	assert.ErrorAs(t, err, &panicked)
+	assert.Equal(t, "something went wrong", panicked.Value)
+	assert.Contains(t, string(panicked.Stack), "TestScheduler_recoverPanics")
+
+	jobOne, err := scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	history := jobOne.History()
+	assert.Len(t, history, 1)
+	assert.EqualError(t, history[0].Error(), "job panicked: something went wrong")
+
+	// Panics in hooks are recovered too, and the other hooks still run.
+	cfg := DefaultSchedulerConfig()
+	cfg.Hooks = Hooks{
+		OnStart:   func(*Job, *RunInfo) { panic("OnStart") },
+		OnFailure: func(*Job, *RunInfo, error) { panic("OnFailure") },
+	}
+	hooksScheduler := StartNewScheduler(cfg)
+	defer func() { _ = StopScheduler(hooksScheduler) }()
+
+	var started, failed atomic.Bool
+	err = hooksScheduler.AddJob(JobConfig{
+		ID:       testID,
+		Schedule: "0 0 1 1 *",
+		Hooks: Hooks{
+			OnStart:   func(*Job, *RunInfo) { started.Store(true) },
+			OnFailure: func(*Job, *RunInfo, error) { failed.Store(true) },
+		},
+		Func: func(ctx context.Context) error { return errors.New("failed") },
+	})
+	assert.NoError(t, err)
+	assert.EqualError(t, hooksScheduler.TriggerJob(context.Background(), testID), "failed")
+	assert.True(t, started.Load())
+	assert.True(t, failed.Load())
+}
+
+func TestScheduler_runStatus(t *testing.T) {
+	t.Parallel()
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		cancel         bool
+		fn             func(ctx context.Context) error
+		expectedStatus RunStatus
+		expectedErr    error
+	}{
+		{
+			name:           "succeeded",
+			fn:             func(ctx context.Context) error { return nil },
+			expectedStatus: RunSucceeded,
+			expectedErr:    nil,
+		},
+		{
+			name:           "failed",
+			fn:             func(ctx context.Context) error { return errFailed },
+			expectedStatus: RunFailed,
+			expectedErr:    errFailed,
+		},
+		{
+			name:    "timed out",
+			timeout: 10 * time.Millisecond,
+			fn: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+			expectedStatus: RunTimedOut,
+			expectedErr:    ErrJobTimeout{},
+		},
+		{
+			name:   "canceled",
+			cancel: true,
+			fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			expectedStatus: RunCanceled,
+			expectedErr:    context.Canceled,
+		},
+		{
+			name:           "panicked",
+			fn:             func(ctx context.Context) error { panic("oops") },
+			expectedStatus: RunPanicked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newTestScheduler(1)
+
+			err := scheduler.AddJob(JobConfig{
+				ID:                "status",
+				Schedule:          "0 0 1 1 *",
+				Timeout:           tt.timeout,
+				ConcurrencyPolicy: ConcurrencyForbid,
+				Func:              tt.fn,
+			})
+			assert.NoError(t, err)
+
+			handle, err := scheduler.TriggerJobAsync(context.Background(), "status")
+			assert.NoError(t, err)
+			if tt.cancel {
+				// A run that is canceled before it starts does not start
+				// at all, so wait for it to start.
+				job, err := scheduler.getJobMetadata("status")
+				assert.NoError(t, err)
+				assert.Eventually(t, func() bool {
+					job.runLock.Lock()
+					defer job.runLock.Unlock()
+					return len(job.active) > 0
+				}, time.Second, time.Millisecond)
+				handle.Cancel()
+			}
+			runErr := handle.Wait()
+
+			job, err := scheduler.GetJob("status")
+			assert.NoError(t, err)
+			history := job.History()
+			assert.Len(t, history, 1)
+			assert.Equal(t, tt.expectedStatus, history[0].Status())
+			assert.Equal(t, runErr, history[0].Error())
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, history[0].Error(), tt.expectedErr)
+			}
+			assert.False(t, history[0].StartedAt().IsZero())
+			assert.False(t, history[0].FinishedAt().Before(history[0].StartedAt()))
+			assert.Equal(t, history[0].FinishedAt().Sub(history[0].StartedAt()), history[0].Duration())
+		})
+	}
+}
+
+func TestScheduler_historyRecordsRunDetails(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	testID := "test-0"
+
+	err := scheduler.AddJob(JobConfig{
+		ID:                testID,
+		Schedule:          "0 0 1 1 *",
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Retry:             &RetryPolicy{MaxAttempts: 2},
+		Func: func(ctx context.Context) error {
+			SetResult(ctx, "processed 42 rows")
+			return errors.New("failed")
+		},
+	})
+	assert.NoError(t, err)
+
+	before := time.Now().UTC()
+	assert.Error(t, scheduler.TriggerJob(context.Background(), testID))
+
+	jobOne, err := scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	history := jobOne.History()
+	assert.Len(t, history, 2)
+
+	for i, h := range history {
+		assert.Equal(t, testID, h.JobID())
+		assert.Equal(t, history[0].RunID(), h.RunID())
+		assert.Equal(t, TriggerManual, h.Trigger())
+		assert.Equal(t, i+1, h.Attempt())
+		assert.Equal(t, "processed 42 rows", h.Result())
+		assert.False(t, h.ScheduledAt().Before(before))
+		assert.Equal(t, h.ScheduledAt(), h.RanAt())
+		assert.Equal(t, h.StartedAt().Sub(h.ScheduledAt()), h.Lag())
+		assert.GreaterOrEqual(t, h.Lag(), time.Duration(0))
+	}
+	assert.NotEmpty(t, history[0].RunID())
+}
+
+func TestScheduler_RunInfoFromContext(t *testing.T) {
+	t.Parallel()
+	var linesLock sync.Mutex
+	var lines []string
+	cfg := DefaultSchedulerConfig()
+	cfg.Logger = funcr.New(func(prefix, args string) {
+		linesLock.Lock()
+		defer linesLock.Unlock()
+		lines = append(lines, prefix+" "+args)
+	}, funcr.Options{})
+	scheduler := StartNewScheduler(cfg)
+	testID := "test-0"
+
+	infos := []*RunInfo{}
+	err := scheduler.AddJob(JobConfig{
+		ID:                testID,
+		Schedule:          "0 0 1 1 *",
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Retry:             &RetryPolicy{MaxAttempts: 2},
+		Func: func(ctx context.Context) error {
+			info, ok := RunInfoFromContext(ctx)
+			assert.True(t, ok)
+			infos = append(infos, info)
+			LoggerFromContext(ctx).Info("hello from the job")
+			return errors.New("failed")
+		},
+	})
+	assert.NoError(t, err)
+	assert.Error(t, scheduler.TriggerJob(context.Background(), testID))
+
+	jobOne, err := scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	history := jobOne.History()
+	assert.Len(t, infos, 2)
+	for i, info := range infos {
+		assert.Equal(t, history[i].runInfo(), info)
+	}
+
+	linesLock.Lock()
+	defer linesLock.Unlock()
+	logged := []string{}
+	for _, line := range lines {
+		if strings.Contains(line, "hello from the job") {
+			logged = append(logged, line)
+		}
+	}
+	assert.Len(t, logged, 2)
+	for i, line := range logged {
+		assert.Contains(t, line, testID)
+		assert.Contains(t, line, fmt.Sprintf(`"run_id"=%q "attempt"=%d`, infos[i].RunID, i+1))
+	}
+
+	_, ok := RunInfoFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, logr.Discard(), LoggerFromContext(context.Background()))
+}
+
+func TestScheduler_stateSurvivesRestart(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "state.json")
+	testID := "test-0"
+
+	newScheduler := func() *Scheduler {
+		store, err := NewFileStateStore(path)
+		assert.NoError(t, err)
+
+		cfg := DefaultSchedulerConfig()
+		cfg.StateStore = store
+		scheduler := StartNewScheduler(cfg)
+
+		err = scheduler.AddJob(JobConfig{
+			ID:                testID,
+			Schedule:          "0 0 1 1 *",
+			ConcurrencyPolicy: ConcurrencyForbid,
+			Func:              func(ctx context.Context) error { return errors.New("failed") },
+		})
+		assert.NoError(t, err)
+
+		return scheduler
+	}
+
+	scheduler := newScheduler()
+	assert.NoError(t, scheduler.PauseJob(testID))
+	assert.Error(t, scheduler.TriggerJob(context.Background(), testID))
+	assert.Error(t, scheduler.TriggerJob(context.Background(), testID))
+
+	scheduler = newScheduler()
+	jobOne, err := scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	assert.True(t, jobOne.Paused())
+	assert.Equal(t, 2, jobOne.State().ConsecutiveFailures)
+	assert.False(t, jobOne.State().LastFailure.IsZero())
+	assert.True(t, jobOne.State().LastSuccess.IsZero())
+	assert.True(t, jobOne.State().LastScheduled.IsZero(), "manual runs are not scheduled runs")
+
+	assert.NoError(t, scheduler.RemoveJob(testID))
+	scheduler = newScheduler()
+	jobOne, err = scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	assert.Equal(t, JobState{}, jobOne.State())
+}
+
+func TestScheduler_middlewareAndHooks(t *testing.T) {
+	t.Parallel()
+	var calls []string
+	var lock sync.Mutex
+	record := func(call string) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls = append(calls, call)
+	}
+
+	middleware := func(name string) Middleware {
+		return func(next JobFunc) JobFunc {
+			return func(ctx context.Context) error {
+				record(name + " before")
+				err := next(ctx)
+				record(name + " after")
+				return err
+			}
+		}
+	}
+
+	hooks := func(name string) Hooks {
+		return Hooks{
+			OnStart: func(job *Job, run *RunInfo) {
+				record(fmt.Sprintf("%s start %s attempt %d", name, job.ID(), run.Attempt))
+			},
+			OnSuccess: func(job *Job, run *RunInfo) {
+				record(fmt.Sprintf("%s success %s", name, run.Trigger))
+			},
+			OnFailure: func(job *Job, run *RunInfo, err error) {
+				record(fmt.Sprintf("%s failure %s", name, err))
+			},
+			OnSkip: func(job *Job, run *RunInfo, err error) {
+				record(fmt.Sprintf("%s skip %s", name, err))
+			},
+		}
+	}
+
+	cfg := DefaultSchedulerConfig()
+	cfg.Middleware = []Middleware{middleware("scheduler")}
+	cfg.Hooks = hooks("scheduler")
+	scheduler := StartNewScheduler(cfg)
+
+	fail := true
+	err := scheduler.AddJob(JobConfig{
+		ID:                "test-0",
+		Schedule:          "0 0 1 1 *",
+		StartingDeadline:  time.Minute,
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Retry:             &RetryPolicy{MaxAttempts: 2},
+		Middleware:        []Middleware{middleware("job")},
+		Hooks:             hooks("job"),
+		Func: func(ctx context.Context) error {
+			record("func")
+			if fail {
+				fail = false
+				return errors.New("failed")
+			}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, scheduler.TriggerJob(context.Background(), "test-0"))
+
+	assert.Equal(t, []string{
+		"scheduler start test-0 attempt 1",
+		"job start test-0 attempt 1",
+		"scheduler before",
+		"job before",
+		"func",
+		"job after",
+		"scheduler after",
+		"scheduler failure failed",
+		"job failure failed",
+		"scheduler start test-0 attempt 2",
+		"job start test-0 attempt 2",
+		"scheduler before",
+		"job before",
+		"func",
+		"job after",
+		"scheduler after",
+		"scheduler success Manual",
+		"job success Manual",
+	}, calls)
+
+	calls = nil
+	job, err := scheduler.getJobMetadata("test-0")
+	assert.NoError(t, err)
+	scheduledTime := time.Now().UTC().Add(-time.Hour)
+	assert.Error(t, job.run(job.newRun(TriggerSchedule, scheduledTime, scheduledTime))(context.Background()))
+	assert.Equal(t, []string{
+		"scheduler skip job could not start before starting deadline",
+		"job skip job could not start before starting deadline",
+	}, calls)
+}
+
+func TestScheduler_Subscribe(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	sub := scheduler.Subscribe(100)
+	slowSub := scheduler.Subscribe(0)
+	testID := "test-0"
+
+	newJobConfig := func(fn JobFunc) JobConfig {
+		return JobConfig{
+			ID:                testID,
+			Schedule:          "0 0 1 1 *",
+			Timeout:           50 * time.Millisecond,
+			ConcurrencyPolicy: ConcurrencyForbid,
+			Func:              fn,
+		}
+	}
+
+	assert.NoError(t, scheduler.AddJob(newJobConfig(func(ctx context.Context) error { return nil })))
+	assert.NoError(t, scheduler.TriggerJob(context.Background(), testID))
+	assert.NoError(t, scheduler.UpdateJob(newJobConfig(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})))
+	assert.ErrorIs(t, scheduler.TriggerJob(context.Background(), testID), ErrJobTimeout{})
+	assert.NoError(t, scheduler.RemoveJob(testID))
+	sub.Close()
+
+	events := []EventType{}
+	for e := range sub.Events() {
+		assert.Equal(t, testID, e.JobID)
+		assert.False(t, e.Time.IsZero())
+		events = append(events, e.Type)
+	}
+
+	assert.Equal(t, []EventType{
+		EventJobAdded,
+		EventRunStarted,
+		EventRunFinished,
+		EventJobUpdated,
+		EventRunStarted,
+		EventRunTimedOut,
+		EventJobRemoved,
+	}, events)
+	assert.Equal(t, uint64(len(events)), slowSub.Dropped())
+}
+
+func TestScheduler_UpdateJob(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(2)
+	testID := "test-0"
+	started, unblock := make(chan struct{}), make(chan struct{})
+
+	err := scheduler.AddJob(JobConfig{
+		ID:                testID,
+		Schedule:          "0 0 1 1 *",
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Func: func(ctx context.Context) error {
+			close(started)
+			<-unblock
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	handle, err := scheduler.TriggerJobAsync(context.Background(), testID)
+	assert.NoError(t, err)
+	<-started
+
+	err = scheduler.UpdateJob(JobConfig{
+		ID:                testID,
+		Schedule:          "0 0 * * *",
+		ConcurrencyPolicy: ConcurrencyForbid,
+		Func:              func(ctx context.Context) error { return nil },
+	})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, scheduler.TriggerJob(context.Background(), testID), ErrJobRunning{}, "runs in progress still count")
+	close(unblock)
+	assert.NoError(t, handle.Wait())
+	assert.NoError(t, scheduler.TriggerJob(context.Background(), testID))
+
+	jobOne, err := scheduler.GetJob(testID)
+	assert.NoError(t, err)
+	assert.Equal(t, "0 0 * * *", jobOne.Schedule())
+	assert.Len(t, jobOne.History(), 3)
+
+	err = scheduler.UpdateJob(JobConfig{ID: "does-not-exist", Schedule: "* * * * *"})
+	assert.EqualError(t, err, "job with ID does-not-exist does not exist")
+}
+
+func TestScheduler_queuedRunsOfChangedJobs(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	unblock := make(chan struct{})
+	assert.NoError(t, scheduler.AddJob(JobConfig{
+		ID:       "blocker",
+		Schedule: "0 0 1 1 *",
+		Func: func(ctx context.Context) error {
+			<-unblock
+			return nil
+		},
+	}))
+
+	var ran sync.Map
+	config := func(id, version string) JobConfig {
+		return JobConfig{
+			ID:       id,
+			Schedule: "0 0 1 1 *",
+			Func: func(ctx context.Context) error {
+				ran.Store(id+"-"+version, true)
+				return nil
+			},
+		}
+	}
+	for _, id := range []string{"removed", "updated", "kept"} {
+		assert.NoError(t, scheduler.AddJob(config(id, "old")))
+	}
+
+	// The only worker is busy, so the scheduled runs wait for it.
+	blocker, err := scheduler.TriggerJobAsync(context.Background(), "blocker")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		scheduler.dispatcher.lock.Lock()
+		defer scheduler.dispatcher.lock.Unlock()
+		return scheduler.dispatcher.running == 1
+	}, time.Second, time.Millisecond)
+
+	now := time.Now().UTC()
+	for _, id := range []string{"removed", "updated", "kept"} {
+		job, err := scheduler.getJobMetadata(id)
+		assert.NoError(t, err)
+		scheduler.submit(job.newRun(TriggerSchedule, now, now))
+	}
+
+	assert.NoError(t, scheduler.RemoveJob("removed"))
+	assert.NoError(t, scheduler.UpdateJob(config("updated", "new")))
+	close(unblock)
+	assert.NoError(t, blocker.Wait())
+
+	assert.Eventually(t, func() bool {
+		_, ok := ran.Load("kept-old")
+		return ok
+	}, time.Second, time.Millisecond)
+	// The runs are started in order, so the dropped ones are done by now.
+	for _, run := range []string{"removed-old", "updated-old", "updated-new"} {
+		_, ok := ran.Load(run)
+		assert.False(t, ok, run)
+	}
+}
+
+func TestScheduler_readdedJobDoesNotCatchUp(t *testing.T) {
+	t.Parallel()
+	scheduler := newTestScheduler(1)
+	job := JobConfig{
+		ID:            "test-0",
+		Schedule:      "* * * * *",
+		MisfirePolicy: MisfireRunAll,
+		Func:          func(ctx context.Context) error { return nil },
+	}
+
+	assert.NoError(t, scheduler.AddJob(job))
+	assert.NoError(t, scheduler.RemoveJob(job.ID))
+
+	// The history outlives the job, and says it last ran ten minutes ago.
+	lastRun := time.Now().UTC().Add(-10 * time.Minute)
+	assert.NoError(t, scheduler.historyStore.Add(&History{jobID: job.ID, trigger: TriggerSchedule, scheduledAt: lastRun}))
+
+	assert.NoError(t, scheduler.AddJob(job))
+	time.Sleep(100 * time.Millisecond)
+	history, err := scheduler.History(HistoryQuery{JobID: job.ID})
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+}
@@ -0,0 +1,118 @@
+package cronroutine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JobState is the state of a job that the Scheduler keeps across restarts
+// when it is configured with a StateStore.
+type JobState struct {
+	// LastScheduled is the most recent fire time the job was due to run
+	// for, whether or not the run was skipped.
+	LastScheduled time.Time `json:"last_scheduled"`
+
+	// LastSuccess is when the job last finished successfully.
+	LastSuccess time.Time `json:"last_success"`
+
+	// LastFailure is when the job last failed, timed out or panicked.
+	LastFailure time.Time `json:"last_failure"`
+
+	// Paused is whether the job is paused.
+	Paused bool `json:"paused"`
+
+	// ConsecutiveFailures is the number of runs that have failed since the
+	// last successful run.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
+
+// StateStore persists JobState. The Scheduler loads the state of a job when
+// it is added and saves it whenever it changes. Implementations must be safe
+// for concurrent use.
+type StateStore interface {
+	// Load returns the saved state of the job, or nil if there is none.
+	Load(jobID string) (*JobState, error)
+
+	// Save stores the state of the job.
+	Save(jobID string, state *JobState) error
+
+	// Delete removes the state of the job.
+	Delete(jobID string) error
+}
+
+// FileStateStore is a StateStore that keeps the state of every job in a single
+// JSON file.
+type FileStateStore struct {
+	lock   sync.Mutex
+	path   string
+	states map[string]*JobState
+}
+
+// NewFileStateStore opens the state file at path. The file is created when
+// state is first saved.
+func NewFileStateStore(path string) (*FileStateStore, error) {
+	s := &FileStateStore{
+		path:   path,
+		states: make(map[string]*JobState),
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &s.states); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *FileStateStore) Load(jobID string) (*JobState, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	state, ok := s.states[jobID]
+	if !ok {
+		return nil, nil
+	}
+
+	ret := *state
+	return &ret, nil
+}
+
+func (s *FileStateStore) Save(jobID string, state *JobState) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	saved := *state
+	s.states[jobID] = &saved
+	return s.write()
+}
+
+func (s *FileStateStore) Delete(jobID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.states[jobID]; !ok {
+		return nil
+	}
+
+	delete(s.states, jobID)
+	return s.write()
+}
+
+func (s *FileStateStore) write() error {
+	return writeFileAtomic(s.path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s.states)
+	})
+}
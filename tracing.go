@@ -0,0 +1,20 @@
+package cronroutine
+
+import "context"
+
+// Tracer traces the attempts of runs, for example with OpenTelemetry through
+// the otelcronroutine package. Its methods are called from the goroutines that
+// run jobs, so they must be safe for concurrent use.
+type Tracer interface {
+	// StartRun is called as an attempt of a run of a job with schedule
+	// starts. The context it returns is passed to the job's function, so
+	// that it can carry a span, and the returned function is called with
+	// the history of the attempt once it has finished.
+	StartRun(ctx context.Context, schedule string, run *RunInfo) (context.Context, func(h *History))
+}
+
+type nopTracer struct{}
+
+func (nopTracer) StartRun(ctx context.Context, _ string, _ *RunInfo) (context.Context, func(*History)) {
+	return ctx, func(*History) {}
+}
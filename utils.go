@@ -1,19 +1,13 @@
 package cronroutine
 
-import "slices"
-
-func getFirstElementGreaterThan(slice []int, value int) int {
-	if slice == nil {
-		panic("slice is nil")
-	}
-	for _, element := range slice {
-		if element > value {
-			return element
-		}
-	}
-
-	return slice[0]
-}
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
 
 func sliceWithStep(minValue int, maxValue int, step int) []int {
 	numbers := make([]int, 0, (maxValue-minValue)/step+1)
@@ -37,3 +31,57 @@ func sortUnique(numbers []int) []int {
 	slices.Sort(sorted)
 	return sorted
 }
+
+// writeFileAtomic writes a file by writing to a temporary file next to it and
+// renaming it into place, so readers never see a partially written file.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmpPath := path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+
+	// Sync the contents before the rename, and the directory after it, so
+	// that a power loss leaves either the old file or the new one rather
+	// than an empty one.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", tmpPath, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes the entries of the directory at path to disk.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer dir.Close()
+
+	if err := dir.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+
+	return nil
+}